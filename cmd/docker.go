@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"main.go/model"
+	"main.go/utils"
+)
+
+// dockerEndpointCmd represents the docker daemon setting of a workspace
+var dockerEndpointCmd = &cobra.Command{
+	Use:   "docker-endpoint",
+	Short: "show or set the docker daemon a workspace runs on, a host with its tls material or a docker cli context",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		reset, err := cmd.Flags().GetBool("reset")
+		cobra.CheckErr(err)
+
+		endpoint := &model.DockerEndpoint{}
+		endpoint.Host, err = cmd.Flags().GetString("host")
+		cobra.CheckErr(err)
+		endpoint.Context, err = cmd.Flags().GetString("context")
+		cobra.CheckErr(err)
+		endpoint.CACert, err = cmd.Flags().GetString("ca-cert")
+		cobra.CheckErr(err)
+		endpoint.Cert, err = cmd.Flags().GetString("cert")
+		cobra.CheckErr(err)
+		endpoint.Key, err = cmd.Flags().GetString("key")
+		cobra.CheckErr(err)
+		endpoint.SkipTLSVerify, err = cmd.Flags().GetBool("skip-tls-verify")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(true, "", "")
+
+		if reset {
+			cobra.CheckErr(workspaceService.SetDockerEndpoint(wsName, nil))
+			return
+		}
+
+		if *endpoint == (model.DockerEndpoint{}) {
+			ws, err := workspaceService.GetWorkspace(wsName)
+			cobra.CheckErr(err)
+			if ws == nil {
+				cobra.CheckErr(fmt.Errorf("workspace %s not found", wsName))
+			}
+			switch {
+			case ws.Docker == nil:
+				fmt.Println("docker endpoint : DOCKER_* environment variables")
+			case ws.Docker.Context != "":
+				fmt.Printf("docker endpoint : context %s\n", ws.Docker.Context)
+			default:
+				fmt.Printf("docker endpoint : host %s\n", ws.Docker.Host)
+			}
+			return
+		}
+
+		cobra.CheckErr(workspaceService.SetDockerEndpoint(wsName, endpoint))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dockerEndpointCmd)
+	dockerEndpointCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	dockerEndpointCmd.Flags().String("host", "", "docker daemon host, e.g. tcp://10.0.0.5:2376 or unix:///run/user/1000/docker.sock")
+	dockerEndpointCmd.Flags().String("context", "", "docker cli context to read the daemon host and tls material from")
+	dockerEndpointCmd.Flags().String("ca-cert", "", "ca certificate the docker host certificate is checked against")
+	dockerEndpointCmd.Flags().String("cert", "", "client certificate presented to the docker host")
+	dockerEndpointCmd.Flags().String("key", "", "client certificate key")
+	dockerEndpointCmd.Flags().Bool("skip-tls-verify", false, "don't verify the docker host certificate")
+	dockerEndpointCmd.Flags().Bool("reset", false, "go back to the DOCKER_* environment variables")
+}
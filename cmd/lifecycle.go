@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"main.go/utils"
+)
+
+// pauseEnvironmentCmd represents perun environment or service pause
+var pauseEnvironmentCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "pause a Perun environment, or a single service of it, keeping its containers and state",
+	Run: func(cmd *cobra.Command, args []string) {
+		verbosity, err := cmd.Flags().GetBool("verbose")
+		cobra.CheckErr(err)
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		service, err := cmd.Flags().GetString("service")
+		cobra.CheckErr(err)
+
+		stop, err := cmd.Flags().GetBool("stop")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(verbosity, "Pausing environment...", "")
+		utils.Logger.Increment(10, "")
+		err = workspaceService.PauseEnvironment(wsName, envName, service, stop)
+		utils.Logger.Finish()
+		cobra.CheckErr(err)
+	},
+}
+
+// resumeEnvironmentCmd represents perun environment or service resume
+var resumeEnvironmentCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "resume a paused or stopped Perun environment, or a single service of it",
+	Run: func(cmd *cobra.Command, args []string) {
+		verbosity, err := cmd.Flags().GetBool("verbose")
+		cobra.CheckErr(err)
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		service, err := cmd.Flags().GetString("service")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(verbosity, "Resuming environment...", "")
+		utils.Logger.Increment(10, "")
+		err = workspaceService.ResumeEnvironment(wsName, envName, service)
+		utils.Logger.Finish()
+		cobra.CheckErr(err)
+	},
+}
+
+// restartServiceCmd represents perun service restart
+var restartServiceCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "restart a single service container of a Perun environment",
+	Run: func(cmd *cobra.Command, args []string) {
+		verbosity, err := cmd.Flags().GetBool("verbose")
+		cobra.CheckErr(err)
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		service, err := cmd.Flags().GetString("service")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(verbosity, "Restarting service...", "")
+		utils.Logger.Increment(10, "")
+		err = workspaceService.RestartService(wsName, envName, service)
+		utils.Logger.Finish()
+		cobra.CheckErr(err)
+	},
+}
+
+// recreateServiceCmd represents perun service recreation
+var recreateServiceCmd = &cobra.Command{
+	Use:   "recreate",
+	Short: "remove a single service container of a Perun environment and create it again from the current spec",
+	Run: func(cmd *cobra.Command, args []string) {
+		verbosity, err := cmd.Flags().GetBool("verbose")
+		cobra.CheckErr(err)
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		service, err := cmd.Flags().GetString("service")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(verbosity, "Recreating service...", "")
+		utils.Logger.Increment(10, "")
+		err = workspaceService.RecreateService(wsName, envName, service)
+		utils.Logger.Finish()
+		cobra.CheckErr(err)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseEnvironmentCmd)
+	pauseEnvironmentCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	pauseEnvironmentCmd.Flags().StringP("env-name", "e", "", "perun environment to pause")
+	pauseEnvironmentCmd.Flags().StringP("service", "s", "", "single service to pause, the whole environment by default")
+	pauseEnvironmentCmd.Flags().Bool("stop", false, "stop the containers instead of freezing them, releasing their memory")
+	pauseEnvironmentCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	pauseEnvironmentCmd.MarkFlagRequired("env-name")
+
+	rootCmd.AddCommand(resumeEnvironmentCmd)
+	resumeEnvironmentCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	resumeEnvironmentCmd.Flags().StringP("env-name", "e", "", "perun environment to resume")
+	resumeEnvironmentCmd.Flags().StringP("service", "s", "", "single service to resume, the whole environment by default")
+	resumeEnvironmentCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	resumeEnvironmentCmd.MarkFlagRequired("env-name")
+
+	rootCmd.AddCommand(restartServiceCmd)
+	restartServiceCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	restartServiceCmd.Flags().StringP("env-name", "e", "", "perun environment of the service")
+	restartServiceCmd.Flags().StringP("service", "s", "", "service to restart")
+	restartServiceCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	restartServiceCmd.MarkFlagRequired("env-name")
+	restartServiceCmd.MarkFlagRequired("service")
+
+	rootCmd.AddCommand(recreateServiceCmd)
+	recreateServiceCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	recreateServiceCmd.Flags().StringP("env-name", "e", "", "perun environment of the service")
+	recreateServiceCmd.Flags().StringP("service", "s", "", "service to recreate")
+	recreateServiceCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	recreateServiceCmd.MarkFlagRequired("env-name")
+	recreateServiceCmd.MarkFlagRequired("service")
+}
@@ -16,33 +16,128 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"main.go/services"
+	"main.go/utils"
 )
 
 // logsCmd represents the logs command
 var logsCmd = &cobra.Command{
 	Use:   "logs",
-	Short: "Get logs from a container",
+	Short: "Get the logs of an environment services",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		serviceNames, err := cmd.Flags().GetStringSlice("service")
+		cobra.CheckErr(err)
+
+		follow, err := cmd.Flags().GetBool("follow")
+		cobra.CheckErr(err)
+
+		since, err := cmd.Flags().GetString("since")
+		cobra.CheckErr(err)
+
+		tail, err := cmd.Flags().GetString("tail")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(true, "", "")
+		env, err := workspaceService.GetEnvironment(wsName, envName)
+		cobra.CheckErr(err)
+
+		err = services.GetLogs(env, services.LogsOptions{
+			Services: serviceNames,
+			Follow:   follow,
+			Since:    since,
+			Tail:     tail,
+			Color:    term.IsTerminal(int(os.Stdout.Fd())),
+		}, os.Stdout, os.Stderr)
+		cobra.CheckErr(err)
+	},
+}
+
+// logsSearchCmd searches the service logs persisted by the perun events listener
+var logsSearchCmd = &cobra.Command{
+	Use:   "search [pattern]",
+	Short: "Search the persisted logs of a workspace services, including removed containers",
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		tail, _ := cmd.Flags().GetBool("tail")
-		container_id, _ := cmd.Flags().GetString("id")
-		services.GetLogs(container_id, tail)
 
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envNames, err := cmd.Flags().GetStringSlice("env-name")
+		cobra.CheckErr(err)
+
+		serviceNames, err := cmd.Flags().GetStringSlice("service")
+		cobra.CheckErr(err)
+
+		sinceValue, err := cmd.Flags().GetString("since")
+		cobra.CheckErr(err)
+
+		untilValue, err := cmd.Flags().GetString("until")
+		cobra.CheckErr(err)
+
+		level, err := cmd.Flags().GetString("level")
+		cobra.CheckErr(err)
+
+		fields, err := cmd.Flags().GetStringToString("field")
+		cobra.CheckErr(err)
+
+		now := time.Now()
+		since, err := services.ParseTimeBound(sinceValue, now)
+		cobra.CheckErr(err)
+		until, err := services.ParseTimeBound(untilValue, now)
+		cobra.CheckErr(err)
+
+		pattern := ""
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+
+		utils.Logger = utils.GetLogger(true, "", "")
+		records, err := services.SearchLogs(wsName, services.LogSearchOptions{
+			Environments: envNames,
+			Services:     serviceNames,
+			Pattern:      pattern,
+			Since:        since,
+			Until:        until,
+			Level:        level,
+			Fields:       fields,
+		})
+		cobra.CheckErr(err)
+
+		for _, record := range records {
+			fmt.Printf("%s/%s | %s %s\n", record.Env, record.Service, record.Timestamp.Local().Format(time.RFC3339), record.Message)
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(logsCmd)
-	logsCmd.Flags().BoolP("tail", "t", false, "Tail the logs")
-	logsCmd.Flags().StringP("id", "i", "", "Container id")
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// logsCmd.PersistentFlags().String("foo", "", "A help for foo")
+	logsCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	logsCmd.Flags().StringP("env-name", "e", "", "perun environment to get the logs of")
+	logsCmd.Flags().StringSliceP("service", "s", nil, "services to get the logs of, all environment services by default")
+	logsCmd.Flags().BoolP("follow", "f", false, "follow the logs output")
+	logsCmd.Flags().String("since", "", "show logs since a timestamp (e.g. 2022-10-01T15:04:05) or a relative duration (e.g. 10m)")
+	logsCmd.Flags().StringP("tail", "t", services.ALL_LOGS, "number of lines to show from the end of the logs of each service")
+	logsCmd.MarkFlagRequired("env-name")
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// logsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	logsCmd.AddCommand(logsSearchCmd)
+	logsSearchCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	logsSearchCmd.Flags().StringSliceP("env-name", "e", nil, "perun environments to search, all workspace environments by default")
+	logsSearchCmd.Flags().StringSliceP("service", "s", nil, "services to search, all services by default")
+	logsSearchCmd.Flags().String("since", "", "only search logs since a timestamp (e.g. 2022-10-01T15:04:05) or a relative duration (e.g. 2h)")
+	logsSearchCmd.Flags().String("until", "", "only search logs until a timestamp (e.g. 2022-10-01T15:04:05) or a relative duration (e.g. 2h)")
+	logsSearchCmd.Flags().String("level", "", "only keep json log lines of the given level")
+	logsSearchCmd.Flags().StringToString("field", nil, "only keep json log lines holding the given field value (e.g. --field user=42)")
 }
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	perun_services "main.go/services"
+	"main.go/utils"
+)
+
+// portsCmd lists the published ports of an environment
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "list the host:container port mappings of every service in an environment",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(true, "", "")
+		env, err := workspaceService.GetEnvironment(wsName, envName)
+		cobra.CheckErr(err)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(writer, "SERVICE\tHOST\tCONTAINER")
+		for _, mapping := range perun_services.GetPortMappings(env) {
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", mapping.Service, mapping.HostPort, mapping.ContainerPort)
+		}
+		writer.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portsCmd)
+	portsCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	portsCmd.Flags().StringP("env-name", "e", "", "perun environment to list the ports of")
+	portsCmd.MarkFlagRequired("env-name")
+}
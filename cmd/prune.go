@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	perun_services "main.go/services"
+	"main.go/utils"
+)
+
+// pruneCmd removes perun resources left behind by failed activations or deleted workspaces
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "find and remove orphaned perun containers, networks, volumes, images and workspace folders",
+	Run: func(cmd *cobra.Command, args []string) {
+		verbosity, err := cmd.Flags().GetBool("verbose")
+		cobra.CheckErr(err)
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		olderThan, err := cmd.Flags().GetDuration("older-than")
+		cobra.CheckErr(err)
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(verbosity, "Pruning perun resources...", "")
+		utils.Logger.Increment(10, "")
+		workspaces, err := runList()
+		cobra.CheckErr(err)
+
+		orphans, pruneErr := perun_services.Prune(workspaces, perun_services.PruneOptions{
+			Workspace: wsName,
+			OlderThan: olderThan,
+			DryRun:    dryRun,
+		})
+		utils.Logger.Finish()
+		fmt.Println()
+
+		if len(orphans) == 0 {
+			fmt.Println("no orphaned perun resources found")
+		} else {
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(writer, "KIND\tNAME\tWORKSPACE\tENV\tAGE\tREASON")
+			for _, orphan := range orphans {
+				age := "-"
+				if !orphan.Created.IsZero() {
+					age = time.Since(orphan.Created).Truncate(time.Second).String()
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", orphan.Kind, orphan.Name, orphan.Workspace, orphan.Env, age, orphan.Reason)
+			}
+			writer.Flush()
+			if dryRun {
+				fmt.Printf("dry run, %d resources would be removed\n", len(orphans))
+			}
+		}
+		cobra.CheckErr(pruneErr)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().StringP("workspace", "w", "", "only prune resources of this workspace")
+	pruneCmd.Flags().Duration("older-than", 0, "only prune resources older than the given age, e.g. 24h")
+	pruneCmd.Flags().Bool("dry-run", false, "list the orphaned resources without removing them")
+	pruneCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"main.go/utils"
+)
+
+// statusCmd shows the environment services state as observed in docker
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show the real container state of every service in an environment",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		reconcile, err := cmd.Flags().GetBool("reconcile")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(true, "", "")
+		statuses, err := workspaceService.GetEnvironmentStatus(wsName, envName, reconcile)
+		cobra.CheckErr(err)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(writer, "SERVICE\tSTATE\tHEALTH\tUPTIME\tRESTARTS\tIMAGE\tPORTS\tDEBUG")
+		for _, status := range statuses {
+			uptime := "-"
+			if !status.StartedAt.IsZero() {
+				uptime = units.HumanDuration(time.Since(status.StartedAt))
+			}
+			health := status.Health
+			if health == "" {
+				health = "-"
+			}
+			image := status.ImageDigest
+			if image == "" {
+				image = "-"
+			}
+			ports := strings.Join(status.Ports, ",")
+			if ports == "" {
+				ports = "-"
+			}
+			debug := "-"
+			if status.Debug {
+				debug = "replaced"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", status.Service, status.State, health, uptime, status.RestartCount, image, ports, debug)
+		}
+		writer.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	statusCmd.Flags().StringP("env-name", "e", "", "perun environment to show the status of")
+	statusCmd.Flags().Bool("reconcile", false, "save the observed statuses in the workspace")
+	statusCmd.MarkFlagRequired("env-name")
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	perun_services "main.go/services"
+	"main.go/utils"
+)
+
+const TOP_REFRESH_INTERVAL = 2 * time.Second
+
+// topCmd shows the live resource usage of an environment services
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "show the live cpu, memory, network and block io usage of every service in an environment",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		wsName, err := cmd.Flags().GetString("workspace")
+		cobra.CheckErr(err)
+
+		envName, err := cmd.Flags().GetString("env-name")
+		cobra.CheckErr(err)
+
+		noStream, err := cmd.Flags().GetBool("no-stream")
+		cobra.CheckErr(err)
+
+		utils.Logger = utils.GetLogger(true, "", "")
+		env, err := workspaceService.GetEnvironment(wsName, envName)
+		cobra.CheckErr(err)
+
+		if noStream {
+			stats, err := perun_services.GetEnvironmentStats(env)
+			cobra.CheckErr(err)
+			out, err := json.MarshalIndent(stats, "", "  ")
+			cobra.CheckErr(err)
+			fmt.Println(string(out))
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err = perun_services.StreamEnvironmentStats(ctx, env, TOP_REFRESH_INTERVAL, printStats)
+		cobra.CheckErr(err)
+	},
+}
+
+func printStats(stats []*perun_services.ServiceStats) {
+	// move the cursor home and clear the screen so the table refreshes in place
+	fmt.Print("\033[H\033[2J")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, "SERVICE\tSTATE\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O\tRESTARTS")
+	for _, s := range stats {
+		fmt.Fprintf(writer, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s / %s\t%s / %s\t%d\n",
+			s.Service,
+			s.State,
+			s.CPUPercent,
+			units.BytesSize(float64(s.MemoryUsage)), units.BytesSize(float64(s.MemoryLimit)),
+			s.MemoryPercent,
+			units.HumanSizeWithPrecision(float64(s.NetworkRx), 3), units.HumanSizeWithPrecision(float64(s.NetworkTx), 3),
+			units.HumanSizeWithPrecision(float64(s.BlockRead), 3), units.HumanSizeWithPrecision(float64(s.BlockWrite), 3),
+			s.RestartCount)
+	}
+	writer.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	topCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
+	topCmd.Flags().StringP("env-name", "e", "", "perun environment to show the usage of")
+	topCmd.Flags().Bool("no-stream", false, "print a single sample as json instead of a refreshing table")
+	topCmd.MarkFlagRequired("env-name")
+}
@@ -36,7 +36,7 @@ var initWorkspaceCmd = &cobra.Command{
 // applyCmd represents perun empty workspace creation
 var applyWorkspaceCmd = &cobra.Command{
 	Use:   "apply",
-	Short: "apply the provided env on a workspace, in dry run mode the environment will be analyzed and persisted but not loaded into the target deployment",
+	Short: "apply the provided env on a workspace, in dry run mode the environment will be analyzed and persisted and the steps of its activation printed, but not loaded into the target deployment",
 	Run: func(cmd *cobra.Command, args []string) {
 		verbosity, err := cmd.Flags().GetBool("verbose")
 		cobra.CheckErr(err)
@@ -75,20 +75,29 @@ var applyWorkspaceCmd = &cobra.Command{
 			}
 		}
 
+		var plan []string
 		if envPath != "" {
 			_, err := perun_services.GetWorkspaceService().ImportLocalEnvironment(wsName, envName, envPath, dbType, dbURL)
 			cobra.CheckErr(err)
 
 			dryRun, err := cmd.Flags().GetBool("dry-run")
 			cobra.CheckErr(err)
-			if !dryRun {
-				err = runActivation(wsName, envName)
+			if dryRun {
+				plan, err = runPlan(wsName, envName)
+				cobra.CheckErr(err)
+			} else {
+				keepOnFailure, err := cmd.Flags().GetBool("keep-on-failure")
+				cobra.CheckErr(err)
+				err = runActivation(wsName, envName, keepOnFailure)
 				cobra.CheckErr(err)
 			}
 
 		}
 
 		utils.Logger.Finish()
+		for i, step := range plan {
+			fmt.Printf("%d. %s\n", i+1, step)
+		}
 	},
 }
 
@@ -116,6 +125,13 @@ var destroyWorkspaceCmd = &cobra.Command{
 			utils.Logger.Increment(10, "")
 			err = runDestroyEnvironment(wsName, envName)
 			cobra.CheckErr(err)
+
+			purge, err := cmd.Flags().GetBool("purge-volumes")
+			cobra.CheckErr(err)
+			if purge {
+				err = workspaceService.PurgeEnvironmentVolumes(wsName, envName)
+				cobra.CheckErr(err)
+			}
 		}
 		utils.Logger.Finish()
 	},
@@ -135,9 +151,12 @@ var activateEnvironmentCmd = &cobra.Command{
 		envName, err := cmd.Flags().GetString("env-name")
 		cobra.CheckErr(err)
 
+		keepOnFailure, err := cmd.Flags().GetBool("keep-on-failure")
+		cobra.CheckErr(err)
+
 		utils.Logger = utils.GetLogger(verbosity, "Activating environment...", "")
 		utils.Logger.Increment(10, "")
-		err = runActivation(wsName, envName)
+		err = runActivation(wsName, envName, keepOnFailure)
 		utils.Logger.Finish()
 		cobra.CheckErr(err)
 	},
@@ -159,6 +178,13 @@ var deactivateEnvironmentCmd = &cobra.Command{
 		utils.Logger = utils.GetLogger(verbosity, "Deactivating environment...", "")
 		utils.Logger.Increment(10, "")
 		err = runDeactivation(wsName, envName)
+		cobra.CheckErr(err)
+
+		purge, err := cmd.Flags().GetBool("purge-volumes")
+		cobra.CheckErr(err)
+		if purge {
+			err = workspaceService.PurgeEnvironmentVolumes(wsName, envName)
+		}
 		utils.Logger.Finish()
 		cobra.CheckErr(err)
 	},
@@ -286,9 +312,12 @@ var importEnvironmentCmd = &cobra.Command{
 
 			excludeList, err := cmd.Flags().GetStringSlice("exclude")
 			cobra.CheckErr(err)
+
+			resourceScale, err := cmd.Flags().GetFloat64("resource-scale")
+			cobra.CheckErr(err)
 			utils.Logger = utils.GetLogger(verbosity, "Importing K8S environment...", "")
 			utils.Logger.Increment(10, "")
-			_, err = workspaceService.ImportK8sEnvironment(workspace, cluster, name, server, token, ca, excludeList, dbType, dbURL)
+			_, err = workspaceService.ImportK8sEnvironment(workspace, cluster, name, server, token, ca, excludeList, resourceScale, dbType, dbURL)
 			cobra.CheckErr(err)
 		}
 
@@ -356,7 +385,8 @@ func init() {
 	applyWorkspaceCmd.Flags().StringP("workspace", "w", "", "perun workspace name, if empty try and fetch from provided env and if not set to default")
 	applyWorkspaceCmd.Flags().StringP("env-name", "e", "", "environment name, overriding the provided env name in path")
 	applyWorkspaceCmd.Flags().StringP("env-path", "p", "", "environment path to load and apply on workspace")
-	applyWorkspaceCmd.Flags().BoolP("dry-run", "d", false, "just generate a dry run output in case it was provided...")
+	applyWorkspaceCmd.Flags().BoolP("dry-run", "d", false, "print the ordered activation plan (networks, pulls, creates, starts) instead of activating the environment")
+	applyWorkspaceCmd.Flags().Bool("keep-on-failure", false, "keep the resources created by a failed activation for debugging instead of rolling them back")
 	applyWorkspaceCmd.Flags().StringP("db-type", "", "", "db type to load (mysql, postgres)")
 	applyWorkspaceCmd.Flags().StringP("db-url", "", "", "db url in the correct db specific format with the credentials if needed")
 	applyWorkspaceCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
@@ -366,6 +396,7 @@ func init() {
 	destroyWorkspaceCmd.Flags().StringP("workspace", "w", "", "perun workspace name")
 	destroyWorkspaceCmd.Flags().StringP("env-name", "e", "", "environment name to destroy, if not provided all environment under workspace will be destroyed")
 	destroyWorkspaceCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	destroyWorkspaceCmd.Flags().Bool("purge-volumes", false, "remove the destroyed environment named volumes, destroying a whole workspace always removes them")
 	destroyWorkspaceCmd.MarkFlagRequired("workspace")
 
 	rootCmd.AddCommand(generateConfigCmd)
@@ -384,6 +415,7 @@ func init() {
 	rootCmd.AddCommand(activateEnvironmentCmd)
 	activateEnvironmentCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
 	activateEnvironmentCmd.Flags().StringP("env-name", "e", "", "perun environment to activate")
+	activateEnvironmentCmd.Flags().Bool("keep-on-failure", false, "keep the resources created by a failed activation for debugging instead of rolling them back")
 	activateEnvironmentCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
 	activateEnvironmentCmd.MarkFlagRequired("env-name")
 
@@ -391,6 +423,7 @@ func init() {
 	deactivateEnvironmentCmd.Flags().StringP("workspace", "w", "default", "perun target workspace name")
 	deactivateEnvironmentCmd.Flags().StringP("env-name", "e", "", "perun environment to deactivate")
 	deactivateEnvironmentCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
+	deactivateEnvironmentCmd.Flags().Bool("purge-volumes", false, "remove the environment named volumes, they are kept by default")
 	deactivateEnvironmentCmd.MarkFlagRequired("env-name")
 
 	rootCmd.AddCommand(importEnvironmentCmd)
@@ -403,6 +436,7 @@ func init() {
 	importEnvironmentCmd.Flags().StringP("token", "", "", "k8s token")
 	importEnvironmentCmd.Flags().StringP("ca", "", "", "k8s certificate authority")
 	importEnvironmentCmd.Flags().StringSliceP("exclude", "e", []string{}, "k8s services to exclude")
+	importEnvironmentCmd.Flags().Float64P("resource-scale", "", 1, "factor applied to the imported k8s cpu and memory limits and requests")
 	importEnvironmentCmd.Flags().StringP("db-type", "", "", "db type to load (mysql, postgres)")
 	importEnvironmentCmd.Flags().StringP("db-url", "", "", "db url in the correct db specific format with the credentials if needed")
 	importEnvironmentCmd.Flags().BoolP("verbose", "v", false, "verbose logger")
@@ -430,8 +464,12 @@ func runList() ([]*model.Workspace, error) {
 	return workspaceService.ListWorkspaces()
 }
 
-func runActivation(workspace string, envName string) error {
-	return workspaceService.ActivateEnvironment(workspace, envName)
+func runActivation(workspace string, envName string, keepOnFailure bool) error {
+	return workspaceService.ActivateEnvironment(workspace, envName, keepOnFailure)
+}
+
+func runPlan(workspace string, envName string) ([]string, error) {
+	return workspaceService.PlanEnvironment(workspace, envName)
 }
 
 func runDeactivation(workspace string, envName string) error {
@@ -9,6 +9,8 @@ const (
 
 const ACTIVE_STATUS = "Active"
 const INACTIVE_STATUS = "Inactive"
+const PAUSED_STATUS = "Paused"
+const STOPPED_STATUS = "Stopped"
 
 func (s WorkspaceMode) String() string {
 	switch s {
@@ -17,3 +19,11 @@ func (s WorkspaceMode) String() string {
 	}
 	return "unknown"
 }
+
+const PULL_ALWAYS = "Always"
+const PULL_IF_NOT_PRESENT = "IfNotPresent"
+const PULL_NEVER = "Never"
+
+const MOUNT_BIND = "bind"
+const MOUNT_VOLUME = "volume"
+const MOUNT_TMPFS = "tmpfs"
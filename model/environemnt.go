@@ -1,5 +1,13 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"main.go/utils"
+)
+
 type Environment struct {
 	Name              string              `yaml:"name"`
 	Description       string              `yaml:"description"`
@@ -8,6 +16,10 @@ type Environment struct {
 	Services          map[string]*Service `yaml:"services"`
 	Status            string              `yaml:"status"`
 	ContainerRegistry *Registry           `yaml:"registry,omitempty"`
+	Parallelism       int                 `yaml:"parallelism,omitempty"`
+	PullPolicy        string              `yaml:"pull_policy,omitempty"`
+	Platform          string              `yaml:"platform,omitempty"`
+	SharedNetwork     bool                `yaml:"shared_network,omitempty"`
 }
 
 type Registry struct {
@@ -24,11 +36,13 @@ type Service struct {
 	Params            map[string]string `yaml:"params"`
 	DependsOn         []string          `yaml:"depends_on,omitempty"`
 	Build             *BuildConfig      `yaml:"build,omitempty"`
-	PreRun            []*Command        `yaml:"pre-run,omitempty"`
+	PreRun            []Command         `yaml:"pre-run,omitempty"`
 	Run               *RunConfig        `yaml:"run"`
 	PostRun           []Command         `yaml:"post-run,omitempty"`
 	Status            string            `yaml:"status"`
 	ContainerRegistry *Registry         `yaml:"registry,omitempty"`
+	PullPolicy        string            `yaml:"pull_policy,omitempty"`
+	Platform          string            `yaml:"platform,omitempty"`
 }
 
 type BuildConfig struct {
@@ -36,24 +50,95 @@ type BuildConfig struct {
 	Params map[string]string `yaml:"params"`
 }
 
+// ExecCommand is an exec form command, each element is passed to the process as is
+type ExecCommand []string
+
+// UnmarshalYAML reads the exec form list, a plain command line from an older workspace is split once into its arguments
+func (c *ExecCommand) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		var args []string
+		if err := value.Decode(&args); err != nil {
+			return err
+		}
+		*c = args
+		return nil
+	}
+	if value.ShortTag() == "!!null" || strings.TrimSpace(value.Value) == "" {
+		*c = nil
+		return nil
+	}
+	args, err := utils.SplitCommand(value.Value)
+	if err != nil {
+		return fmt.Errorf("invalid command at line %d : %v", value.Line, err)
+	}
+	*c = args
+	return nil
+}
+
+// Command is a pre-run or post-run step, pre-run steps may run in another image than the service one
 type Command struct {
-	Cmd  string   `yaml:"cmd"`
-	Args []string `yaml:"args"`
+	Cmd   ExecCommand `yaml:"cmd"`
+	Args  []string    `yaml:"args"`
+	Shell bool        `yaml:"shell,omitempty"`
+	Image string      `yaml:"image,omitempty"`
 }
 
 type RunConfig struct {
-	Cmd    string           `yaml:"cmd"`
-	Args   []string         `yaml:"args"`
-	EnVars []EnVar          `yaml:"envars"`
-	Ports  []Port           `yaml:"ports"`
-	Mounts map[string]Mount `yaml:"mounts"`
+	Cmd         ExecCommand      `yaml:"cmd"`
+	Args        []string         `yaml:"args"`
+	Shell       bool             `yaml:"shell,omitempty"`
+	EnVars      []EnVar          `yaml:"envars"`
+	Ports       []Port           `yaml:"ports"`
+	Mounts      map[string]Mount `yaml:"mounts"`
+	HealthCheck *HealthCheck     `yaml:"healthcheck,omitempty"`
+	Resources   *Resources       `yaml:"resources,omitempty"`
+	Entrypoint  []string         `yaml:"entrypoint,omitempty"`
+	WorkingDir  string           `yaml:"working_dir,omitempty"`
+	User        string           `yaml:"user,omitempty"`
+	Hostname    string           `yaml:"hostname,omitempty"`
+	ExtraHosts  []string         `yaml:"extra_hosts,omitempty"`
+	DNS         []string         `yaml:"dns,omitempty"`
+	DNSSearch   []string         `yaml:"dns_search,omitempty"`
+	DNSOptions  []string         `yaml:"dns_options,omitempty"`
+	Restart     string           `yaml:"restart,omitempty"`
+	StopTimeout string           `yaml:"stop_timeout,omitempty"`
+}
+
+// Resources holds the service cpu and memory limits and reservations
+type Resources struct {
+	Limits       *ResourceList `yaml:"limits,omitempty"`
+	Reservations *ResourceList `yaml:"reservations,omitempty"`
+}
+
+// ResourceList cpus are a number of cores (0.5 or 500m), memory accepts kubernetes or docker notation (512Mi, 1g)
+type ResourceList struct {
+	CPUs   string `yaml:"cpus,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// HealthCheck describes how to probe a service for readiness, Type is one of http, tcp or exec, http and tcp probes run
+// inside the container with curl or wget and nc or bash, images lacking them are probed from the host instead
+type HealthCheck struct {
+	Type         string   `yaml:"type"`
+	Port         string   `yaml:"port,omitempty"`
+	Path         string   `yaml:"path,omitempty"`
+	Cmd          []string `yaml:"cmd,omitempty"`
+	Interval     string   `yaml:"interval,omitempty"`
+	Timeout      string   `yaml:"timeout,omitempty"`
+	StartPeriod  string   `yaml:"start_period,omitempty"`
+	Retries      int      `yaml:"retries,omitempty"`
+	ReadyTimeout string   `yaml:"ready_timeout,omitempty"`
 }
 
+// Mount Type is one of bind (default), volume or tmpfs, volumes are named docker volumes scoped to the workspace and environment
 type Mount struct {
 	Name       string   `yaml:"name"`
+	Type       string   `yaml:"type,omitempty"`
 	SourcePath string   `yaml:"source_path"`
 	Path       string   `yaml:"path"`
 	Configs    []Config `yaml:"files"`
+	ReadOnly   bool     `yaml:"read_only,omitempty"`
+	Size       string   `yaml:"size,omitempty"`
 }
 
 type Config struct {
@@ -61,10 +146,12 @@ type Config struct {
 	Content    string `yaml:"content"`
 }
 
+// Port HostPort accepts a port number or auto, in which case the port picked by perun is saved in AssignedHostPort
 type Port struct {
-	Port     string `yaml:"port"`
-	HostPort string `yaml:"hostport"`
-	Exposed  bool   `yaml:"exposed"`
+	Port             string `yaml:"port"`
+	HostPort         string `yaml:"hostport"`
+	Exposed          bool   `yaml:"exposed"`
+	AssignedHostPort string `yaml:"assigned_hostport,omitempty"`
 }
 
 type EnVar struct {
@@ -4,5 +4,17 @@ type Workspace struct {
 	Name string `yaml:"name"`
 	Mode string `yaml:"mode"`
 
-	Environments []*Environment `yaml:"environments"`
+	Environments []*Environment  `yaml:"environments"`
+	Docker       *DockerEndpoint `yaml:"docker,omitempty"`
+}
+
+// DockerEndpoint is the docker daemon a workspace runs on, either a host with its tls material or a docker cli context,
+// the DOCKER_* environment variables apply when none is set
+type DockerEndpoint struct {
+	Host          string `yaml:"host,omitempty"`
+	Context       string `yaml:"context,omitempty"`
+	CACert        string `yaml:"ca_cert,omitempty"`
+	Cert          string `yaml:"cert,omitempty"`
+	Key           string `yaml:"key,omitempty"`
+	SkipTLSVerify bool   `yaml:"skip_tls_verify,omitempty"`
 }
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const BUILD_ARG_PREFIX = "arg."
+const BUILD_LABEL_PREFIX = "label."
+
+func isDockerfileBuild(service *model.Service) bool {
+	return service.Build != nil && service.Build.Type == "dockerfile"
+}
+
+// GetServiceImageTag returns the tag of a locally built service image, unique per workspace/env/service
+func GetServiceImageTag(env *model.Environment, service *model.Service) string {
+	return strings.ToLower(fmt.Sprintf("perun/%s/%s/%s:latest", env.Workspace, env.Name, service.Name))
+}
+
+// BuildServiceImage builds the service image out of its Dockerfile build config and returns the resulting image tag
+//
+// supported build params :
+//   - context : build context directory, relative to the source dir when one is given, defaults to the service location param
+//   - dockerfile : Dockerfile path relative to the context, defaults to Dockerfile
+//   - target : target build stage
+//   - arg.<NAME> : build arg NAME
+//   - label.<KEY> : image label KEY
+func BuildServiceImage(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, sourceDir string, platform string) (string, error) {
+
+	params := getBuildParams(service)
+
+	contextDir := params["context"]
+	if sourceDir != "" {
+		contextDir = filepath.Join(sourceDir, contextDir)
+	} else if contextDir == "" {
+		contextDir = service.Params["location"]
+	}
+	if contextDir == "" {
+		return "", fmt.Errorf("failed to build service %s image, no build context provided", service.Name)
+	}
+
+	options := getImageBuildOptions(env, service, platform)
+	dockerfile := filepath.FromSlash(options.Dockerfile)
+
+	exists, err := Exists(filepath.Join(contextDir, dockerfile))
+	if err != nil || !exists {
+		return "", fmt.Errorf("failed to build service %s image, Dockerfile %s not found under %s", service.Name, dockerfile, contextDir)
+	}
+
+	excludes, err := utils.ReadIgnoreFile(filepath.Join(contextDir, ".dockerignore"))
+	if err != nil {
+		return "", fmt.Errorf("failed to build service %s image, failed to read .dockerignore : %v", service.Name, err)
+	}
+	// the daemon needs the Dockerfile even when .dockerignore excludes it, docker build keeps it the same way
+	excludes = append(excludes, "!"+filepath.ToSlash(filepath.Clean(dockerfile)), "!.dockerignore")
+
+	tag := options.Tags[0]
+	utils.Logger.Info("building image %s for service %s out of %s", tag, service.Name, filepath.Join(contextDir, dockerfile))
+
+	buildContext, err := utils.TarDirectory(contextDir, excludes)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service %s image, invalid .dockerignore : %v", service.Name, err)
+	}
+	defer buildContext.Close()
+
+	resp, err := cli.ImageBuild(ctx, buildContext, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service %s image : %v", service.Name, err)
+	}
+	defer resp.Body.Close()
+
+	err = jsonmessage.DisplayJSONMessagesStream(resp.Body, utils.Logger.GetOutput(), 0, false, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service %s image : %v", service.Name, err)
+	}
+
+	return tag, nil
+}
+
+func getBuildParams(service *model.Service) map[string]string {
+	if service.Build != nil && service.Build.Params != nil {
+		return service.Build.Params
+	}
+	return map[string]string{}
+}
+
+// getImageBuildOptions returns the docker build options of the service image, tagged, labeled and parametrized after its build params
+func getImageBuildOptions(env *model.Environment, service *model.Service, platform string) types.ImageBuildOptions {
+
+	params := getBuildParams(service)
+
+	dockerfile := params["dockerfile"]
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	buildArgs := make(map[string]*string)
+	labels := map[string]string{
+		"provider":        "perun",
+		"perun-workspace": env.Workspace,
+		"perun-env":       env.Name,
+		"perun-service":   service.Name,
+	}
+	for key, value := range params {
+		value := value
+		if strings.HasPrefix(key, BUILD_ARG_PREFIX) {
+			buildArgs[strings.TrimPrefix(key, BUILD_ARG_PREFIX)] = &value
+		} else if strings.HasPrefix(key, BUILD_LABEL_PREFIX) {
+			labels[strings.TrimPrefix(key, BUILD_LABEL_PREFIX)] = value
+		}
+	}
+
+	return types.ImageBuildOptions{
+		Tags:        []string{GetServiceImageTag(env, service)},
+		Dockerfile:  filepath.ToSlash(dockerfile),
+		Target:      params["target"],
+		BuildArgs:   buildArgs,
+		Labels:      labels,
+		Remove:      true,
+		ForceRemove: true,
+		Platform:    platform,
+	}
+}
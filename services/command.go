@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+
+	"main.go/model"
+)
+
+// GetContainerCommand returns the exec form command overriding the image CMD, like kubernetes args the command and its
+// arguments are passed as is, a shell is only used when the service asks for one, the script is then passed to the
+// shell entrypoint returned by GetContainerEntrypoint
+func GetContainerCommand(service *model.Service) []string {
+
+	runConfig := service.Run
+	if len(runConfig.Cmd) == 0 {
+		if len(runConfig.Args) > 0 {
+			return runConfig.Args
+		}
+		return nil
+	}
+
+	if runConfig.Shell {
+		return []string{getShellCommand(runConfig.Cmd, runConfig.Args)}
+	}
+
+	return append(append([]string{}, runConfig.Cmd...), runConfig.Args...)
+}
+
+// GetContainerEntrypoint returns the entrypoint overriding the image ENTRYPOINT, a shell command runs through /bin/sh
+// rather than being passed to the image entrypoint, nil keeps the image entrypoint
+func GetContainerEntrypoint(service *model.Service) []string {
+
+	runConfig := service.Run
+	if runConfig.Shell && len(runConfig.Cmd) > 0 {
+		return []string{"/bin/sh", "-c"}
+	}
+	if len(runConfig.Entrypoint) > 0 {
+		return runConfig.Entrypoint
+	}
+	return nil
+}
+
+// getShellCommand renders a command line for /bin/sh, the command is kept as shell syntax while each argument is quoted
+func getShellCommand(cmd []string, args []string) string {
+
+	line := strings.Join(cmd, " ")
+	for _, arg := range args {
+		line += " " + shellQuote(arg)
+	}
+	return line
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`&|;<>()*?[]#~{}!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
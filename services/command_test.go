@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGetContainerCommand(t *testing.T) {
+
+	service := &model.Service{
+		Name: "testService",
+		Run: &model.RunConfig{
+			Cmd:  model.ExecCommand{"node", "server.js"},
+			Args: []string{"--title", "hello world"},
+		},
+	}
+
+	assert.Equal(t, []string{"node", "server.js", "--title", "hello world"}, GetContainerCommand(service))
+
+	service.Run.Shell = true
+	assert.Equal(t, []string{"node server.js --title 'hello world'"}, GetContainerCommand(service))
+	assert.Equal(t, []string{"/bin/sh", "-c"}, GetContainerEntrypoint(service))
+
+	service.Run = &model.RunConfig{Args: []string{"--port", "8080"}}
+	assert.Equal(t, []string{"--port", "8080"}, GetContainerCommand(service))
+
+	service.Run = &model.RunConfig{Cmd: model.ExecCommand{"/opt/my app/run"}}
+	assert.Equal(t, []string{"/opt/my app/run"}, GetContainerCommand(service))
+}
+
+func TestSynchronizeShellCommand(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	env := getTestSyncEnvironment()
+	// the nginx image runs its own /docker-entrypoint.sh, the shell command must not be handed to it
+	env.Services["api"].Run.Cmd = model.ExecCommand{"nginx -g 'daemon off;'"}
+	env.Services["api"].Run.Shell = true
+	assert.Nil(t, DockerSynchronizationService{Runtime: runtime}.Synchronize(env))
+
+	inspect, err := runtime.ContainerInspect(context.Background(), GetContainerName(env, "api"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/bin/sh", "-c"}, []string(inspect.Config.Entrypoint))
+	assert.Equal(t, []string{"nginx -g 'daemon off;'"}, []string(inspect.Config.Cmd))
+}
+
+func TestUnmarshalExecCommand(t *testing.T) {
+
+	var run model.RunConfig
+	assert.Nil(t, yaml.Unmarshal([]byte("cmd: [java, -jar, /opt/my app/app.jar]\n"), &run))
+	assert.Equal(t, model.ExecCommand{"java", "-jar", "/opt/my app/app.jar"}, run.Cmd)
+
+	// a command line from an older workspace is split once when loaded
+	run = model.RunConfig{}
+	assert.Nil(t, yaml.Unmarshal([]byte("cmd: java -jar \"/opt/my app/app.jar\"\n"), &run))
+	assert.Equal(t, model.ExecCommand{"java", "-jar", "/opt/my app/app.jar"}, run.Cmd)
+
+	run = model.RunConfig{}
+	assert.Nil(t, yaml.Unmarshal([]byte("cmd: \"\"\nargs: [--port, \"8080\"]\n"), &run))
+	assert.Nil(t, run.Cmd)
+	assert.Equal(t, []string{"--port", "8080"}, run.Args)
+
+	assert.NotNil(t, yaml.Unmarshal([]byte("cmd: echo \"unterminated\n"), &run))
+}
@@ -71,9 +71,12 @@ func (cg *LocalConfigGenerator) Generate(workspaceName string, environmentName s
 	}
 
 	if command != "" {
-		commandArr := strings.Split(command, " ")
-		service.Run.Cmd = commandArr[0]
-		service.Run.Args = commandArr[1:]
+		commandArr, err := utils.SplitCommand(command)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate config for service %s : %v", serviceName, err)
+		}
+		service.Run.Cmd = commandArr
+		service.Run.Args = nil
 	}
 
 	if configType != "vscode" {
@@ -144,14 +147,18 @@ func (*VSCodeConfigGenerator) GetTaskConfig(environment *model.Environment, serv
 		Label: "docker-build",
 		// Platform: "python", //TODO retrieve type from code analysis
 		DockerBuild: &VSCodeDockerBuild{
-			Tag:           environment.Workspace + environment.Name + service.Name + ":latest",
-			DockerFile:    "${workspaceFolder}/Dockerfile", //TODO retrieve from user config and default to this value
-			Context:       "${workspaceFolder}",
-			Pull:          true,
-			CustomOptions: "--platform linux/amd64",
+			Tag:        environment.Workspace + environment.Name + service.Name + ":latest",
+			DockerFile: "${workspaceFolder}/Dockerfile", //TODO retrieve from user config and default to this value
+			Context:    "${workspaceFolder}",
+			Pull:       true,
 		},
 	}
 
+	// without an explicit platform docker builds for the host native one
+	if platform := GetPlatformOverride(environment, service); platform != "" {
+		dockerBuild.DockerBuild.CustomOptions = "--platform " + platform
+	}
+
 	dockerRun := &VSCodeTask{
 		Type:      "docker-run",
 		Label:     "docker-run: debug",
@@ -179,20 +186,19 @@ func (*VSCodeConfigGenerator) GetTaskConfig(environment *model.Environment, serv
 		},
 	}
 
+	command := append(append([]string{}, service.Run.Cmd...), service.Run.Args...)
+
 	if service.Params["source"] == "python" {
 		dockerBuild.Platform = "python"
 
-		command := service.Run.Cmd
-		args := service.Run.Args
-
-		if service.Run.Cmd == "python" {
-			command = service.Run.Args[0]
-			args = service.Run.Args[1:]
+		if len(command) > 0 && command[0] == "python" {
+			command = command[1:]
 		}
 
-		dockerRun.Python = &VSCodePythonExec{
-			File: command,
-			Args: args,
+		dockerRun.Python = &VSCodePythonExec{}
+		if len(command) > 0 {
+			dockerRun.Python.File = command[0]
+			dockerRun.Python.Args = command[1:]
 		}
 	} else if service.Params["source"] == "node" {
 
@@ -211,31 +217,31 @@ func (*VSCodeConfigGenerator) GetTaskConfig(environment *model.Environment, serv
 			EnableDebugging: true,
 		}
 
-		if service.Run.Cmd == "node" {
-			dockerRun.DockerRun.Command = service.Run.Cmd + " --inspect=0.0.0.0:9229 " + strings.Join(service.Run.Args, " ")
+		if len(command) > 0 && command[0] == "node" {
+			dockerRun.DockerRun.Command = "node --inspect=0.0.0.0:9229 " + strings.Join(command[1:], " ")
 
 			// 	debugPortmapping := VSCodeConfigPortMapping{
 			// 		ContainerPort: "9229",
 			// 	}
 
 			// 	dockerRun.DockerRun.Ports = append(dockerRun.DockerRun.Ports, debugPortmapping)
-		} else if service.Run.Cmd == "nest" {
-			dockerRun.DockerRun.Command = service.Run.Cmd
-			for _, arg := range service.Run.Args {
+		} else if len(command) > 0 && command[0] == "nest" {
+			dockerRun.DockerRun.Command = "nest"
+			for _, arg := range command[1:] {
 				if arg == "start" {
 					arg += " --debug=0.0.0.0:9229"
 				}
 				dockerRun.DockerRun.Command += " " + arg
 			}
 		} else {
-			dockerRun.DockerRun.Command = "node  --inspect=0.0.0.0:9229 " + service.Run.Cmd + strings.Join(service.Run.Args, " ")
+			dockerRun.DockerRun.Command = "node --inspect=0.0.0.0:9229 " + strings.Join(command, " ")
 		}
 
 		dockerRun.DockerRun.CustomOptions = "--entrypoint=\"\" "
 
 	}
 
-	dockerRun.DockerRun.Network = environment.Workspace
+	dockerRun.DockerRun.Network = GetNetworkName(environment)
 	dockerRun.DockerRun.NetworkAlias = service.Name
 	dockerRun.DockerRun.CustomOptions += "--workdir=/app"
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -80,6 +81,9 @@ func (d MySQLCopy) dump(connectionString string) (string, error) {
 	}
 
 	// Open the output file
+	if err := os.MkdirAll(filepath.Dir(d.TargetFile), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create dump folder: %v", err)
+	}
 	file, err := os.OpenFile(d.TargetFile, os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
 		return "", err
@@ -345,6 +349,9 @@ func (d PostgresCopy) dump(connectionString string) (string, error) {
 	}
 
 	// Open the output file
+	if err := os.MkdirAll(filepath.Dir(d.TargetFile), os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create dump folder: %v", err)
+	}
 	file, err := os.OpenFile(d.TargetFile, os.O_APPEND|os.O_CREATE, 0666)
 	if err != nil {
 		return "", err
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"main.go/model"
+)
+
+// GetServiceWaves groups the environment services into start waves based on their depends_on declarations,
+// services in the same wave are independent of each other and every service comes after all of its dependencies
+func GetServiceWaves(env *model.Environment, skip ...string) ([][]*model.Service, error) {
+
+	skipped := make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	inDegree := make(map[string]int)
+	dependents := make(map[string][]string)
+	for name, service := range env.Services {
+		if skipped[name] {
+			continue
+		}
+		inDegree[name] += 0
+		for _, dependency := range service.DependsOn {
+			if skipped[dependency] {
+				continue
+			}
+			if _, ok := env.Services[dependency]; !ok {
+				return nil, fmt.Errorf("service %s depends on unknown service %s in environment %s", name, dependency, env.Name)
+			}
+			if dependency == name {
+				return nil, fmt.Errorf("service %s in environment %s depends on itself", name, env.Name)
+			}
+			inDegree[name]++
+			dependents[dependency] = append(dependents[dependency], name)
+		}
+	}
+
+	waves := make([][]*model.Service, 0)
+	current := make([]string, 0)
+	for name, degree := range inDegree {
+		if degree == 0 {
+			current = append(current, name)
+		}
+	}
+
+	ordered := 0
+	for len(current) > 0 {
+		sort.Strings(current)
+		wave := make([]*model.Service, 0, len(current))
+		next := make([]string, 0)
+		for _, name := range current {
+			wave = append(wave, env.Services[name])
+			for _, dependent := range dependents[name] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ordered += len(wave)
+		waves = append(waves, wave)
+		current = next
+	}
+
+	if ordered != len(inDegree) {
+		cycle := make([]string, 0)
+		for name, degree := range inDegree {
+			if degree > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("cyclic dependency between services %s in environment %s", strings.Join(cycle, ", "), env.Name)
+	}
+
+	return waves, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getWaveNames(waves [][]*model.Service) [][]string {
+	names := make([][]string, 0)
+	for _, wave := range waves {
+		waveNames := make([]string, 0)
+		for _, service := range wave {
+			waveNames = append(waveNames, service.Name)
+		}
+		names = append(names, waveNames)
+	}
+	return names
+}
+
+func TestGetServiceWaves(t *testing.T) {
+
+	env := &model.Environment{
+		Name: "testEnv",
+		Services: map[string]*model.Service{
+			"frontend":        {Name: "frontend", DependsOn: []string{"checkoutservice", "cartservice"}},
+			"checkoutservice": {Name: "checkoutservice", DependsOn: []string{"cartservice", "perun-db"}},
+			"cartservice":     {Name: "cartservice", DependsOn: []string{"redis-cart"}},
+			"redis-cart":      {Name: "redis-cart"},
+			"adservice":       {Name: "adservice"},
+			"perun-db":        {Name: "perun-db"},
+		},
+	}
+
+	waves, err := GetServiceWaves(env, "perun-db")
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"adservice", "redis-cart"},
+		{"cartservice"},
+		{"checkoutservice"},
+		{"frontend"},
+	}, getWaveNames(waves))
+}
+
+func TestGetServiceWavesCycle(t *testing.T) {
+
+	env := &model.Environment{
+		Name: "testEnv",
+		Services: map[string]*model.Service{
+			"a": {Name: "a", DependsOn: []string{"c"}},
+			"b": {Name: "b", DependsOn: []string{"a"}},
+			"c": {Name: "c", DependsOn: []string{"b"}},
+			"d": {Name: "d"},
+		},
+	}
+
+	_, err := GetServiceWaves(env)
+
+	assert.EqualError(t, err, "cyclic dependency between services a, b, c in environment testEnv")
+}
+
+func TestGetServiceWavesUnknownDependency(t *testing.T) {
+
+	env := &model.Environment{
+		Name: "testEnv",
+		Services: map[string]*model.Service{
+			"a": {Name: "a", DependsOn: []string{"missing"}},
+		},
+	}
+
+	_, err := GetServiceWaves(env)
+
+	assert.EqualError(t, err, "service a depends on unknown service missing in environment testEnv")
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const DEFAULT_DOCKER_CONTEXT = "default"
+
+// DockerClientFactory creates the docker clients of every perun call site, so they all honour the workspace docker endpoint
+type DockerClientFactory struct {
+	PersistenceService WorkspacePersistenceService
+	DockerConfigDir    string
+}
+
+var dockerClients = DockerClientFactory{PersistenceService: LocalPersistenceService{}}
+
+// GetDockerClient returns a client to the docker daemon the workspace runs on
+func GetDockerClient(workspace string) (*client.Client, error) {
+	return dockerClients.ForWorkspace(workspace)
+}
+
+func (f DockerClientFactory) ForWorkspace(workspace string) (*client.Client, error) {
+	var endpoint *model.DockerEndpoint
+	if workspace != "" {
+		ws, err := f.PersistenceService.GetWorkspace(workspace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workspace %s docker endpoint : %v", workspace, err)
+		}
+		if ws != nil {
+			endpoint = ws.Docker
+		}
+	}
+	return f.ForEndpoint(endpoint)
+}
+
+func (f DockerClientFactory) ForEndpoint(endpoint *model.DockerEndpoint) (*client.Client, error) {
+
+	resolved, err := f.Resolve(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == nil {
+		return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	}
+	if strings.HasPrefix(resolved.Host, "ssh://") {
+		return nil, fmt.Errorf("unsupported docker host %s, ssh endpoints are not supported, forward the remote socket or use tcp", resolved.Host)
+	}
+
+	opts := []client.Opt{}
+	if resolved.CACert != "" || resolved.Cert != "" || resolved.Key != "" || resolved.SkipTLSVerify {
+		tlsConfig, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             resolved.CACert,
+			CertFile:           resolved.Cert,
+			KeyFile:            resolved.Key,
+			InsecureSkipVerify: resolved.SkipTLSVerify,
+			ExclusiveRootPools: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load docker host %s tls material : %v", resolved.Host, err)
+		}
+		// the http client goes first, the host then sets up its transport for the host protocol
+		opts = append(opts, client.WithHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}))
+	}
+	opts = append(opts, client.WithHost(resolved.Host), client.WithAPIVersionNegotiation())
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client for host %s : %v", resolved.Host, err)
+	}
+	return cli, nil
+}
+
+// ForWorkspaces returns a client per distinct docker daemon the workspaces run on, keyed by daemon host,
+// the daemon of the DOCKER_* environment variables is always part of them
+func (f DockerClientFactory) ForWorkspaces(workspaces []*model.Workspace) (map[string]*client.Client, error) {
+
+	cli, err := f.ForEndpoint(nil)
+	if err != nil {
+		return nil, err
+	}
+	clients := map[string]*client.Client{cli.DaemonHost(): cli}
+
+	for _, ws := range workspaces {
+		if ws.Docker == nil {
+			continue
+		}
+		cli, err := f.ForEndpoint(ws.Docker)
+		if err != nil {
+			utils.Logger.Warn("skipping workspace %s docker endpoint : %v", ws.Name, err)
+			continue
+		}
+		if _, ok := clients[cli.DaemonHost()]; ok {
+			cli.Close()
+			continue
+		}
+		clients[cli.DaemonHost()] = cli
+	}
+	return clients, nil
+}
+
+// Resolve turns a docker cli context into its host and tls material, nil means the DOCKER_* environment variables apply
+func (f DockerClientFactory) Resolve(endpoint *model.DockerEndpoint) (*model.DockerEndpoint, error) {
+	if endpoint == nil {
+		return nil, nil
+	}
+	if endpoint.Context != "" {
+		if endpoint.Host != "" {
+			return nil, fmt.Errorf("invalid docker endpoint, set either a host or a context, not both")
+		}
+		return f.readDockerContext(endpoint.Context)
+	}
+	if endpoint.Host == "" {
+		return nil, nil
+	}
+	return endpoint, nil
+}
+
+type dockerContextMeta struct {
+	Name      string
+	Endpoints map[string]struct {
+		Host          string
+		SkipTLSVerify bool
+	}
+}
+
+// readDockerContext reads a context the way the docker cli stores it, the metadata and the tls material
+// are kept in folders named after the context name digest
+func (f DockerClientFactory) readDockerContext(name string) (*model.DockerEndpoint, error) {
+	if name == DEFAULT_DOCKER_CONTEXT {
+		return nil, nil
+	}
+
+	configDir := f.DockerConfigDir
+	if configDir == "" {
+		configDir = os.Getenv("DOCKER_CONFIG")
+	}
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch home directory : %v", err)
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+
+	digest := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(digest[:])
+
+	data, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("docker context %s not found", name)
+		}
+		return nil, fmt.Errorf("failed to read docker context %s : %v", name, err)
+	}
+	meta := dockerContextMeta{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse docker context %s : %v", name, err)
+	}
+	dockerEndpoint, ok := meta.Endpoints["docker"]
+	if !ok || dockerEndpoint.Host == "" {
+		return nil, fmt.Errorf("docker context %s has no docker endpoint", name)
+	}
+
+	endpoint := &model.DockerEndpoint{
+		Host:          dockerEndpoint.Host,
+		SkipTLSVerify: dockerEndpoint.SkipTLSVerify,
+	}
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	for file, target := range map[string]*string{"ca.pem": &endpoint.CACert, "cert.pem": &endpoint.Cert, "key.pem": &endpoint.Key} {
+		location := filepath.Join(tlsDir, file)
+		if _, err := os.Stat(location); err == nil {
+			*target = location
+		}
+	}
+	return endpoint, nil
+}
+
+// isLocalDaemon tells whether the docker daemon runs on this machine, so it sees its files and publishes ports on it,
+// sockets and pipes are local, tcp endpoints only when they target a loopback address
+func isLocalDaemon(host string) bool {
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return false
+	}
+	switch parsed.Scheme {
+	case "unix", "npipe":
+		return true
+	case "tcp", "http", "https":
+		hostname := parsed.Hostname()
+		if hostname == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(hostname)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"main.go/model"
+)
+
+func TestResolveDockerContext(t *testing.T) {
+	configDir := t.TempDir()
+	digest := sha256.Sum256([]byte("remote"))
+	id := hex.EncodeToString(digest[:])
+
+	metaDir := filepath.Join(configDir, "contexts", "meta", id)
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	assert.Nil(t, os.MkdirAll(metaDir, os.ModePerm))
+	assert.Nil(t, os.MkdirAll(tlsDir, os.ModePerm))
+	assert.Nil(t, os.WriteFile(filepath.Join(metaDir, "meta.json"), []byte(`{"Name":"remote","Endpoints":{"docker":{"Host":"tcp://10.0.0.5:2376","SkipTLSVerify":false}}}`), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(tlsDir, "ca.pem"), []byte{}, 0644))
+
+	factory := DockerClientFactory{DockerConfigDir: configDir}
+
+	endpoint, err := factory.Resolve(&model.DockerEndpoint{Context: "remote"})
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp://10.0.0.5:2376", endpoint.Host)
+	assert.Equal(t, filepath.Join(tlsDir, "ca.pem"), endpoint.CACert)
+	assert.Equal(t, "", endpoint.Cert)
+
+	endpoint, err = factory.Resolve(&model.DockerEndpoint{Context: DEFAULT_DOCKER_CONTEXT})
+	assert.Nil(t, err)
+	assert.Nil(t, endpoint)
+
+	_, err = factory.Resolve(&model.DockerEndpoint{Context: "missing"})
+	assert.NotNil(t, err)
+
+	_, err = factory.Resolve(&model.DockerEndpoint{Context: "remote", Host: "tcp://10.0.0.5:2376"})
+	assert.NotNil(t, err)
+}
+
+func TestForWorkspaceEndpoint(t *testing.T) {
+	ps := new(DummyPersistenceService)
+	ps.On("GetWorkspace", "remote").Return(&model.Workspace{Name: "remote", Docker: &model.DockerEndpoint{Host: "tcp://10.0.0.5:2375"}}, nil)
+
+	factory := DockerClientFactory{PersistenceService: ps}
+	cli, err := factory.ForWorkspace("remote")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp://10.0.0.5:2375", cli.DaemonHost())
+
+	ps.AssertExpectations(t)
+}
+
+func TestSetDockerEndpointActiveEnvironment(t *testing.T) {
+	setTestLogger(t)
+	wss := GetWorkspaceService()
+
+	ps := new(DummyPersistenceService)
+	wss.PersistenceService = ps
+	ps.On("GetWorkspace", "test").Return(&model.Workspace{
+		Name:         "test",
+		Environments: []*model.Environment{{Name: "testEnv", Status: model.PAUSED_STATUS}},
+	}, nil)
+
+	err := wss.SetDockerEndpoint("test", &model.DockerEndpoint{Host: "tcp://10.0.0.5:2375"})
+	assert.NotNil(t, err)
+
+	ps.AssertNotCalled(t, "PersistWorkspace")
+}
+
+func TestIsLocalDaemon(t *testing.T) {
+	assert.True(t, isLocalDaemon("unix:///var/run/docker.sock"))
+	assert.True(t, isLocalDaemon("npipe:////./pipe/docker_engine"))
+	assert.True(t, isLocalDaemon("tcp://localhost:2375"))
+	assert.True(t, isLocalDaemon("tcp://127.0.0.1:2376"))
+	assert.False(t, isLocalDaemon("tcp://10.0.0.5:2375"))
+	assert.False(t, isLocalDaemon("tcp://build.example.com:2376"))
+}
@@ -15,10 +15,17 @@ import (
 
 type EnvironmentService interface {
 	CreateEnvironment(name string) (*model.Environment, error)
-	ActivateEnvironment(env *model.Environment) error
+	ActivateEnvironment(env *model.Environment, keepOnFailure bool) error
 	DeactivateEnvironment(env *model.Environment) error
 	DestroyEnvironment(env *model.Environment) error
 	SyncEnvironment(env *model.Environment) error
+	PurgeEnvironment(env *model.Environment) error
+	PauseEnvironment(env *model.Environment, service string, stop bool) error
+	ResumeEnvironment(env *model.Environment, service string) error
+	RestartService(env *model.Environment, service string) error
+	RecreateService(env *model.Environment, service string) error
+	GetEnvironmentStatus(env *model.Environment) ([]*ServiceStatus, error)
+	PlanEnvironment(env *model.Environment) ([]string, error)
 }
 
 type LocalEnvironmentService struct {
@@ -106,7 +113,7 @@ func CheckEventsListener() (bool, error) {
 	return false, nil
 }
 
-func (es LocalEnvironmentService) ActivateEnvironment(env *model.Environment) error {
+func (es LocalEnvironmentService) ActivateEnvironment(env *model.Environment, keepOnFailure bool) error {
 
 	utils.Logger.Info("Activating environment %s", env.Name)
 
@@ -136,7 +143,7 @@ func (es LocalEnvironmentService) ActivateEnvironment(env *model.Environment) er
 
 	}
 
-	err = es.SynchronizationService.Synchronize(env)
+	err = es.SynchronizationService.Activate(env, keepOnFailure)
 	if err != nil {
 		return err
 	}
@@ -147,7 +154,7 @@ func (es LocalEnvironmentService) ActivateEnvironment(env *model.Environment) er
 func (es LocalEnvironmentService) DeactivateEnvironment(env *model.Environment) error {
 
 	utils.Logger.Info("Deactivating environment %s", env.Name)
-	if env.Status != model.ACTIVE_STATUS {
+	if !isStartedStatus(env.Status) {
 		return fmt.Errorf("deactivation aborted, Target environment %s not in active state", env.Name)
 	}
 
@@ -163,7 +170,7 @@ func (es LocalEnvironmentService) DestroyEnvironment(env *model.Environment) err
 
 	utils.Logger.Info("Destroying environment %s", env.Name)
 
-	if env.Status != model.ACTIVE_STATUS {
+	if !isStartedStatus(env.Status) {
 		if env.Status == model.INACTIVE_STATUS {
 			utils.Logger.Info("Environment %s is in inactive state... skipping environment deletion", env.Name)
 			return nil
@@ -207,3 +214,98 @@ func (es LocalEnvironmentService) SyncEnvironment(env *model.Environment) error
 
 	return nil
 }
+
+// PurgeEnvironment removes the persistent data of the environment, named volumes survive deactivation and are only removed here
+func (es LocalEnvironmentService) PurgeEnvironment(env *model.Environment) error {
+
+	utils.Logger.Info("Purging environment %s volumes", env.Name)
+	if env.Target.Type != "local" {
+		return nil
+	}
+
+	return es.SynchronizationService.PurgeVolumes(env)
+}
+
+// PauseEnvironment pauses or stops a single service, or the whole environment when no service is given
+func (es LocalEnvironmentService) PauseEnvironment(env *model.Environment, service string, stop bool) error {
+
+	if !isStartedStatus(env.Status) {
+		return fmt.Errorf("pause aborted, Target environment %s is not active", env.Name)
+	}
+
+	err := es.SynchronizationService.Pause(env, service, stop)
+	if err != nil {
+		return err
+	}
+
+	if service == "" {
+		if stop {
+			env.Status = model.STOPPED_STATUS
+		} else {
+			env.Status = model.PAUSED_STATUS
+		}
+	}
+	return nil
+}
+
+// ResumeEnvironment resumes a single paused or stopped service, or the whole environment when no service is given
+func (es LocalEnvironmentService) ResumeEnvironment(env *model.Environment, service string) error {
+
+	if !isStartedStatus(env.Status) {
+		return fmt.Errorf("resume aborted, Target environment %s is not active, activate it instead", env.Name)
+	}
+
+	err := es.SynchronizationService.Resume(env, service)
+	if err != nil {
+		return err
+	}
+
+	if service == "" {
+		env.Status = model.ACTIVE_STATUS
+	}
+	return nil
+}
+
+func (es LocalEnvironmentService) RestartService(env *model.Environment, service string) error {
+
+	if !isStartedStatus(env.Status) {
+		return fmt.Errorf("restart aborted, Target environment %s is not active", env.Name)
+	}
+
+	return es.SynchronizationService.Restart(env, service)
+}
+
+func (es LocalEnvironmentService) RecreateService(env *model.Environment, service string) error {
+
+	if !isStartedStatus(env.Status) {
+		return fmt.Errorf("recreate aborted, Target environment %s is not active", env.Name)
+	}
+
+	return es.SynchronizationService.Recreate(env, service)
+}
+
+func (es LocalEnvironmentService) GetEnvironmentStatus(env *model.Environment) ([]*ServiceStatus, error) {
+	return es.SynchronizationService.Observe(env)
+}
+
+// PlanEnvironment validates the environment and returns the steps its activation would take, nothing is loaded
+func (es LocalEnvironmentService) PlanEnvironment(env *model.Environment) ([]string, error) {
+
+	utils.Logger.Info("Planning environment %s", env.Name)
+
+	err := es.ValidationService.ValidateEnvironment(env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range env.Services {
+
+		err = es.ValidationService.ValidateService(service)
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return es.SynchronizationService.Plan(env)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const GIT_REPOSITORY_PARAM = "repository"
+const GIT_REF_PARAM = "ref"
+
+const gitFetchStateFile = ".perun-ref"
+
+// FetchGitSource clones the git service repository at the requested ref into the workspace service src folder
+// and returns its location, an existing checkout is reused as long as the repository and ref did not change,
+// a branch checkout is brought up to date with the latest commit of the branch
+func FetchGitSource(env *model.Environment, service *model.Service) (string, error) {
+
+	repository, ref, err := getGitParams(service)
+	if err != nil {
+		return "", err
+	}
+
+	serviceLocation, err := getServiceLocation(env, service)
+	if err != nil {
+		return "", err
+	}
+
+	srcLocation := serviceLocation + "src"
+	stateLocation := serviceLocation + gitFetchStateFile
+	state := repository + "\n" + ref
+
+	previousState, err := os.ReadFile(stateLocation)
+	if err == nil && string(previousState) == state {
+		if exists, _ := Exists(srcLocation + "/.git"); exists {
+			if !isGitBranch(srcLocation, ref) {
+				utils.Logger.Info("service %s source is up to date with %s@%s", service.Name, repository, ref)
+				return srcLocation, nil
+			}
+			// a branch moves on, its latest commit is fetched into the existing checkout
+			utils.Logger.Info("updating service %s source to the latest %s@%s", service.Name, repository, ref)
+			err = updateGitBranch(srcLocation, ref)
+			if err == nil {
+				return srcLocation, nil
+			}
+			utils.Logger.Warn("failed to update service %s source, cloning it again : %v", service.Name, err)
+		}
+	}
+
+	utils.Logger.Info("cloning service %s source from %s@%s", service.Name, repository, ref)
+	if err := os.RemoveAll(srcLocation); err != nil {
+		return "", fmt.Errorf("failed to clear service %s source folder %s : %v", service.Name, srcLocation, err)
+	}
+	os.Remove(stateLocation)
+
+	if _, err := runGit("", "clone", "--quiet", "--", repository, srcLocation); err != nil {
+		return "", fmt.Errorf("failed to clone service %s repository %s : %v", service.Name, repository, err)
+	}
+
+	if ref != "" {
+		err = checkoutGitRef(srcLocation, ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout ref %s of service %s repository %s : %v", ref, service.Name, repository, err)
+		}
+	}
+
+	if err := os.WriteFile(stateLocation, []byte(state), 0644); err != nil {
+		return "", fmt.Errorf("failed to persist service %s source state : %v", service.Name, err)
+	}
+
+	return srcLocation, nil
+}
+
+// getGitParams returns the repository and ref of a git service
+func getGitParams(service *model.Service) (string, string, error) {
+
+	repository := service.Params[GIT_REPOSITORY_PARAM]
+	if repository == "" {
+		return "", "", fmt.Errorf("failed to fetch service %s source, missing %s param", service.Name, GIT_REPOSITORY_PARAM)
+	}
+	ref := service.Params[GIT_REF_PARAM]
+	// git would read them as options
+	if strings.HasPrefix(repository, "-") {
+		return "", "", fmt.Errorf("invalid service %s %s param %s, it can't start with -", service.Name, GIT_REPOSITORY_PARAM, repository)
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", "", fmt.Errorf("invalid service %s %s param %s, it can't start with -", service.Name, GIT_REF_PARAM, ref)
+	}
+	return repository, ref, nil
+}
+
+// checkoutGitRef checks out a branch, tag or commit in detached mode, remote branches are resolved through origin,
+// the trailing -- keeps the ref from being read as a path
+func checkoutGitRef(repoLocation string, ref string) error {
+
+	var lastErr error
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		if _, err := runGit(repoLocation, "rev-parse", "--verify", "--quiet", "--end-of-options", candidate+"^{commit}"); err != nil {
+			lastErr = err
+			continue
+		}
+		_, err := runGit(repoLocation, "-c", "advice.detachedHead=false", "checkout", "--quiet", "--detach", candidate, "--")
+		return err
+	}
+
+	// commits that are not reachable from any advertised ref need an explicit fetch
+	if _, err := runGit(repoLocation, "fetch", "--quiet", "--", "origin", ref); err != nil {
+		return fmt.Errorf("unknown ref : %v", lastErr)
+	}
+	_, err := runGit(repoLocation, "-c", "advice.detachedHead=false", "checkout", "--quiet", "--detach", "FETCH_HEAD", "--")
+	return err
+}
+
+// isGitBranch tells whether the ref is a branch of the cloned repository, no ref stands for the default branch
+func isGitBranch(repoLocation string, ref string) bool {
+	if ref == "" {
+		return true
+	}
+	_, err := runGit(repoLocation, "rev-parse", "--verify", "--quiet", "--end-of-options", "refs/remotes/origin/"+ref)
+	return err == nil
+}
+
+func updateGitBranch(repoLocation string, ref string) error {
+
+	if _, err := runGit(repoLocation, "fetch", "--quiet", "--prune", "--", "origin"); err != nil {
+		return err
+	}
+	target := "origin/HEAD"
+	if ref != "" {
+		target = "origin/" + ref
+	}
+	_, err := runGit(repoLocation, "-c", "advice.detachedHead=false", "checkout", "--quiet", "--force", "--detach", target, "--")
+	return err
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed : %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func getServiceLocation(env *model.Environment, service *model.Service) (string, error) {
+	dirname, err := os.UserHomeDir()
+	if err != nil {
+		utils.Logger.Error("%v", err)
+		err = fmt.Errorf("failed to create service folder, failed to fetch home directory : %v", err)
+		return "", err
+	}
+	serviceLocation := dirname + utils.WORKSPACES_HOME + env.Workspace + "/" + service.Name + "/"
+	err = os.MkdirAll(serviceLocation, os.ModePerm)
+	if err != nil {
+		utils.Logger.Error("%v", err)
+		err = fmt.Errorf("failed to create service folder: %v", err)
+		return "", err
+	}
+
+	return serviceLocation, nil
+}
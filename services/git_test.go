@@ -0,0 +1,129 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"main.go/model"
+	"main.go/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gitCommand(t *testing.T, dir string, args ...string) string {
+	out, err := runGit(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func commitFile(t *testing.T, repo string, name string, content string) string {
+	err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644)
+	assert.Nil(t, err)
+	gitCommand(t, repo, "add", name)
+	gitCommand(t, repo, "-c", "user.name=perun", "-c", "user.email=perun@example.com", "commit", "--quiet", "-m", name)
+	return gitCommand(t, repo, "rev-parse", "HEAD")
+}
+
+func TestFetchGitSource(t *testing.T) {
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	home := setTestLogger(t)
+
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	gitCommand(t, "", "init", "--quiet", "--bare", origin)
+
+	work := filepath.Join(t.TempDir(), "work")
+	gitCommand(t, "", "clone", "--quiet", origin, work)
+	gitCommand(t, work, "checkout", "--quiet", "-b", "main")
+	firstCommit := commitFile(t, work, "Dockerfile", "FROM alpine\n")
+	gitCommand(t, work, "tag", "v1")
+	gitCommand(t, work, "checkout", "--quiet", "-b", "feature")
+	commitFile(t, work, "feature.txt", "feature\n")
+	gitCommand(t, work, "push", "--quiet", "origin", "main", "feature", "v1")
+
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS"}
+	service := &model.Service{
+		Name: "gitservice",
+		Type: "git",
+		Params: map[string]string{
+			GIT_REPOSITORY_PARAM: "file://" + origin,
+			GIT_REF_PARAM:        "feature",
+		},
+	}
+
+	src, err := FetchGitSource(env, service)
+	assert.Nil(t, err)
+	assert.Equal(t, home+utils.WORKSPACES_HOME+"testWS/gitservice/src", src)
+	assert.FileExists(t, filepath.Join(src, "feature.txt"))
+
+	// an unchanged ref reuses the existing checkout
+	marker := filepath.Join(src, "marker")
+	assert.Nil(t, os.WriteFile(marker, []byte{}, 0644))
+	_, err = FetchGitSource(env, service)
+	assert.Nil(t, err)
+	assert.FileExists(t, marker)
+
+	// a new commit on the branch is fetched into the existing checkout
+	gitCommand(t, work, "checkout", "--quiet", "feature")
+	latestCommit := commitFile(t, work, "latest.txt", "latest\n")
+	gitCommand(t, work, "push", "--quiet", "origin", "feature")
+	_, err = FetchGitSource(env, service)
+	assert.Nil(t, err)
+	assert.FileExists(t, marker)
+	assert.Equal(t, latestCommit, gitCommand(t, src, "rev-parse", "HEAD"))
+
+	service.Params[GIT_REF_PARAM] = "v1"
+	src, err = FetchGitSource(env, service)
+	assert.Nil(t, err)
+	assert.NoFileExists(t, marker)
+	assert.NoFileExists(t, filepath.Join(src, "feature.txt"))
+
+	service.Params[GIT_REF_PARAM] = firstCommit
+	src, err = FetchGitSource(env, service)
+	assert.Nil(t, err)
+	assert.Equal(t, firstCommit, gitCommand(t, src, "rev-parse", "HEAD"))
+
+	service.Params[GIT_REF_PARAM] = "missing"
+	_, err = FetchGitSource(env, service)
+	assert.NotNil(t, err)
+
+	// params are never passed to git as options
+	service.Params[GIT_REF_PARAM] = "--upload-pack=touch pwned"
+	_, err = FetchGitSource(env, service)
+	assert.EqualError(t, err, "invalid service gitservice ref param --upload-pack=touch pwned, it can't start with -")
+	service.Params[GIT_REF_PARAM] = "main"
+	service.Params[GIT_REPOSITORY_PARAM] = "-c core.sshCommand=touch pwned"
+	_, err = FetchGitSource(env, service)
+	assert.EqualError(t, err, "invalid service gitservice repository param -c core.sshCommand=touch pwned, it can't start with -")
+}
+
+func TestPlanGitService(t *testing.T) {
+	home := setTestLogger(t)
+
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS", Services: map[string]*model.Service{
+		"gitservice": {
+			Name:   "gitservice",
+			Type:   "git",
+			Params: map[string]string{GIT_REPOSITORY_PARAM: "https://git.example.com/app.git", GIT_REF_PARAM: "main"},
+			Build:  &model.BuildConfig{Type: "dockerfile", Params: map[string]string{"dockerfile": "docker/Dockerfile"}},
+			Run:    &model.RunConfig{},
+		},
+	}}
+
+	// the plan is built out of the declared params, the repository is never reached
+	plan, err := DockerSynchronizationService{Runtime: NewMemoryRuntime()}.Plan(env)
+	assert.Nil(t, err)
+	assert.Equal(t, "fetch service gitservice source https://git.example.com/app.git@main", plan[1])
+	assert.Equal(t, "build image "+GetServiceImageTag(env, env.Services["gitservice"])+" for platform linux/amd64", plan[2])
+
+	// nothing is written to the workspace
+	_, err = os.Stat(filepath.Join(home, utils.WORKSPACES_HOME, "testWS", "gitservice"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const DEFAULT_HEALTHCHECK_INTERVAL = 5 * time.Second
+const DEFAULT_HEALTHCHECK_TIMEOUT = 3 * time.Second
+const DEFAULT_HEALTHCHECK_RETRIES = 3
+const DEFAULT_READY_TIMEOUT = 2 * time.Minute
+
+const readinessPollInterval = time.Second
+
+// GetHealthConfig maps a perun service health check onto the docker container health check, http and tcp checks run
+// curl or wget and nc or bash inside the container, WaitForService probes images lacking them from the host
+func GetHealthConfig(hc *model.HealthCheck) (*container.HealthConfig, error) {
+
+	if hc == nil {
+		return nil, nil
+	}
+
+	var test []string
+	switch hc.Type {
+	case "http":
+		if hc.Port == "" {
+			return nil, fmt.Errorf("http health check requires a port")
+		}
+		path := hc.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		url := "http://localhost:" + hc.Port + path
+		test = []string{"CMD-SHELL", fmt.Sprintf("curl -fsS -o /dev/null %s || wget -q -O /dev/null %s || exit 1", url, url)}
+	case "tcp":
+		if hc.Port == "" {
+			return nil, fmt.Errorf("tcp health check requires a port")
+		}
+		test = []string{"CMD-SHELL", fmt.Sprintf("nc -z localhost %s || bash -c 'echo > /dev/tcp/localhost/%s' || exit 1", hc.Port, hc.Port)}
+	case "exec":
+		if len(hc.Cmd) == 0 {
+			return nil, fmt.Errorf("exec health check requires a command")
+		}
+		test = append([]string{"CMD"}, hc.Cmd...)
+	default:
+		return nil, fmt.Errorf("unsupported health check type %s", hc.Type)
+	}
+
+	interval, err := parseDuration(hc.Interval, DEFAULT_HEALTHCHECK_INTERVAL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check interval %s : %v", hc.Interval, err)
+	}
+	timeout, err := parseDuration(hc.Timeout, DEFAULT_HEALTHCHECK_TIMEOUT)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check timeout %s : %v", hc.Timeout, err)
+	}
+	startPeriod, err := parseDuration(hc.StartPeriod, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check start period %s : %v", hc.StartPeriod, err)
+	}
+	retries := hc.Retries
+	if retries == 0 {
+		retries = DEFAULT_HEALTHCHECK_RETRIES
+	}
+
+	return &container.HealthConfig{
+		Test:        test,
+		Interval:    interval,
+		Timeout:     timeout,
+		StartPeriod: startPeriod,
+		Retries:     retries,
+	}, nil
+}
+
+// GetDBHealthCheck returns the default readiness probe of a perun loaded db
+func GetDBHealthCheck(dbType string) *model.HealthCheck {
+	switch dbType {
+	case "mysql":
+		return &model.HealthCheck{
+			Type:         "exec",
+			Cmd:          []string{"mysqladmin", "ping", "-h", "127.0.0.1", "--silent"},
+			ReadyTimeout: "5m",
+		}
+	case "postgres":
+		return &model.HealthCheck{
+			Type:         "exec",
+			Cmd:          []string{"pg_isready", "-h", "127.0.0.1"},
+			ReadyTimeout: "5m",
+		}
+	}
+	return nil
+}
+
+// WaitForService blocks until the service container is healthy, or running in case no health check was defined,
+// the http and tcp checks of an image without a shell or probe tools, like distroless or scratch ones, are run from the host
+func WaitForService(ctx context.Context, cli ContainerRuntime, containerID string, service *model.Service) error {
+
+	readyTimeout := DEFAULT_READY_TIMEOUT
+	var hc *model.HealthCheck
+	if service.Run != nil && service.Run.HealthCheck != nil {
+		hc = service.Run.HealthCheck
+		var err error
+		readyTimeout, err = parseDuration(hc.ReadyTimeout, DEFAULT_READY_TIMEOUT)
+		if err != nil {
+			return fmt.Errorf("invalid ready timeout %s for service %s : %v", hc.ReadyTimeout, service.Name, err)
+		}
+	}
+
+	utils.Logger.Info("waiting up to %s for service %s to become ready", readyTimeout, service.Name)
+	deadline := time.Now().Add(readyTimeout)
+	lastProbe := "no probe result yet"
+	for {
+		inspect, err := cli.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect service %s container : %v", service.Name, err)
+		}
+
+		state := inspect.State
+		if state.Health != nil && len(state.Health.Log) > 0 {
+			result := state.Health.Log[len(state.Health.Log)-1]
+			lastProbe = describeProbe(result)
+
+			if state.Running && hc != nil && (hc.Type == "http" || hc.Type == "tcp") && isProbeToolMissing(result) {
+				err := probeFromHost(inspect, hc)
+				if err == nil {
+					utils.Logger.Info("service %s is ready, probed from the host as its image has no %s probe tools", service.Name, hc.Type)
+					return nil
+				}
+				lastProbe = fmt.Sprintf("%s, the image lacks a shell with curl or wget (http) or nc or bash (tcp) and the probe from the host failed : %v", lastProbe, err)
+			}
+		}
+
+		if !state.Running && !state.Restarting {
+			return fmt.Errorf("service %s container is %s (exit code %d) and never became ready, last probe : %s", service.Name, state.Status, state.ExitCode, lastProbe)
+		}
+		if state.Running && (state.Health == nil || state.Health.Status == types.Healthy) {
+			utils.Logger.Info("service %s is ready", service.Name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s never became ready within %s, last probe : %s", service.Name, readyTimeout, lastProbe)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// isProbeToolMissing tells whether the docker probe could not run at all, the image has no shell or no probe command,
+// rather than the service not answering yet
+func isProbeToolMissing(result *types.HealthcheckResult) bool {
+	output := strings.ToLower(result.Output)
+	return result.ExitCode == -1 || result.ExitCode == 126 || result.ExitCode == 127 ||
+		strings.Contains(output, "not found") || strings.Contains(output, "no such file or directory")
+}
+
+// probeFromHost runs the http or tcp health check from the host, against the published port of the health check first
+// then against the container addresses on its networks, which only a host sharing the docker networks can reach
+func probeFromHost(inspect types.ContainerJSON, hc *model.HealthCheck) error {
+
+	timeout, err := parseDuration(hc.Timeout, DEFAULT_HEALTHCHECK_TIMEOUT)
+	if err != nil {
+		return err
+	}
+
+	addresses := make([]string, 0)
+	if inspect.NetworkSettings != nil {
+		for _, binding := range inspect.NetworkSettings.Ports[nat.Port(hc.Port+"/tcp")] {
+			hostIP := binding.HostIP
+			if hostIP == "" || hostIP == "0.0.0.0" || hostIP == "::" {
+				hostIP = "127.0.0.1"
+			}
+			addresses = append(addresses, net.JoinHostPort(hostIP, binding.HostPort))
+		}
+		for _, endpoint := range inspect.NetworkSettings.Networks {
+			if endpoint != nil && endpoint.IPAddress != "" {
+				addresses = append(addresses, net.JoinHostPort(endpoint.IPAddress, hc.Port))
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return fmt.Errorf("port %s is neither published nor reachable on a container network", hc.Port)
+	}
+
+	path := hc.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	httpClient := http.Client{Timeout: timeout}
+
+	var lastErr error
+	for _, address := range addresses {
+		if hc.Type == "tcp" {
+			conn, err := net.DialTimeout("tcp", address, timeout)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+			lastErr = err
+			continue
+		}
+		// like curl -f, an error status fails the probe
+		resp, err := httpClient.Get("http://" + address + path)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusBadRequest {
+				return nil
+			}
+			err = fmt.Errorf("http://%s%s returned %s", address, path, resp.Status)
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func describeProbe(result *types.HealthcheckResult) string {
+	output := strings.TrimSpace(result.Output)
+	if output == "" {
+		output = "no output"
+	}
+	return fmt.Sprintf("exit code %d, %s", result.ExitCode, output)
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
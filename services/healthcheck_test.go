@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHealthConfig(t *testing.T) {
+
+	hc, err := GetHealthConfig(&model.HealthCheck{Type: "http", Port: "8080", Path: "healthz", Interval: "2s"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"CMD-SHELL", "curl -fsS -o /dev/null http://localhost:8080/healthz || wget -q -O /dev/null http://localhost:8080/healthz || exit 1"}, hc.Test)
+	assert.Equal(t, 2*time.Second, hc.Interval)
+	assert.Equal(t, DEFAULT_HEALTHCHECK_TIMEOUT, hc.Timeout)
+	assert.Equal(t, DEFAULT_HEALTHCHECK_RETRIES, hc.Retries)
+
+	hc, err = GetHealthConfig(&model.HealthCheck{Type: "exec", Cmd: []string{"redis-cli", "ping"}, Retries: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"CMD", "redis-cli", "ping"}, hc.Test)
+	assert.Equal(t, 10, hc.Retries)
+
+	_, err = GetHealthConfig(&model.HealthCheck{Type: "tcp"})
+	assert.EqualError(t, err, "tcp health check requires a port")
+
+	_, err = GetHealthConfig(&model.HealthCheck{Type: "grpc"})
+	assert.EqualError(t, err, "unsupported health check type grpc")
+
+	hc, err = GetHealthConfig(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, hc)
+}
+
+// unhealthyRuntime reports the given last probe result for every container, reachable on the loopback address
+type unhealthyRuntime struct {
+	*MemoryRuntime
+	probe *types.HealthcheckResult
+}
+
+func (r *unhealthyRuntime) ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error) {
+	inspect, err := r.MemoryRuntime.ContainerInspect(ctx, container)
+	if err == nil {
+		inspect.State.Health = &types.Health{Status: types.Unhealthy, Log: []*types.HealthcheckResult{r.probe}}
+		inspect.NetworkSettings = &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"testNetwork": {IPAddress: "127.0.0.1"}}}
+	}
+	return inspect, err
+}
+
+func TestWaitForServiceWithoutProbeTools(t *testing.T) {
+	setTestLogger(t)
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	ctx := context.Background()
+	runtime := &unhealthyRuntime{MemoryRuntime: NewMemoryRuntime(), probe: &types.HealthcheckResult{
+		ExitCode: -1,
+		Output:   "OCI runtime exec failed: exec: \"/bin/sh\": stat /bin/sh: no such file or directory: unknown",
+	}}
+	resp, err := runtime.ContainerCreate(ctx, &container.Config{Image: "distroless"}, &container.HostConfig{}, nil, nil, "api")
+	assert.Nil(t, err)
+	assert.Nil(t, runtime.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}))
+
+	// a distroless image can't run the probe, the host runs it instead
+	service := &model.Service{Name: "api", Run: &model.RunConfig{HealthCheck: &model.HealthCheck{Type: "http", Port: port, Path: "/healthz", ReadyTimeout: "1s"}}}
+	assert.Nil(t, WaitForService(ctx, runtime, resp.ID, service))
+
+	service.Run.HealthCheck.Type = "tcp"
+	assert.Nil(t, WaitForService(ctx, runtime, resp.ID, service))
+
+	service.Run.HealthCheck.Type = "http"
+	status = http.StatusServiceUnavailable
+	err = WaitForService(ctx, runtime, resp.ID, service)
+	assert.ErrorContains(t, err, "the image lacks a shell with curl or wget (http) or nc or bash (tcp) and the probe from the host failed")
+	assert.ErrorContains(t, err, "503 Service Unavailable")
+
+	// a probe that ran and failed is not run again from the host
+	status = http.StatusOK
+	runtime.probe = &types.HealthcheckResult{ExitCode: 1, Output: "curl: (7) Failed to connect to localhost"}
+	err = WaitForService(ctx, runtime, resp.ID, service)
+	assert.EqualError(t, err, "service api never became ready within 1s, last probe : exit code 1, curl: (7) Failed to connect to localhost")
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// getHookCommand returns the exec form of a pre-run or post-run step, a shell is only used when the step asks for one
+func getHookCommand(step *model.Command) []string {
+
+	if len(step.Cmd) == 0 {
+		return step.Args
+	}
+	if step.Shell {
+		return []string{"/bin/sh", "-c", getShellCommand(step.Cmd, step.Args)}
+	}
+	return append(append([]string{}, step.Cmd...), step.Args...)
+}
+
+// runPreRunSteps runs the service pre-run steps in order as one-shot init containers sharing the service image, env and mounts
+func runPreRunSteps(ctx context.Context, cli ContainerRuntime, targetNetworkID string, env *model.Environment, service *model.Service, config *container.Config, hostConfig *container.HostConfig, plt *v1.Platform) error {
+
+	for i := range service.PreRun {
+		step := &service.PreRun[i]
+
+		cmd := getHookCommand(step)
+
+		stepConfig := *config
+		stepConfig.Healthcheck = nil
+		stepConfig.ExposedPorts = nil
+		stepConfig.Labels = map[string]string{"provider": "perun", "provider-mode": "init", "perun-workspace": env.Workspace, "perun-env": env.Name, "perun-service": service.Name}
+		stepConfig.Cmd = cmd
+		if step.Image != "" {
+			// the service user and working dir belong to the service image
+			stepConfig.Image = step.Image
+			stepConfig.Entrypoint = nil
+			stepConfig.User = ""
+			stepConfig.WorkingDir = ""
+		}
+		if len(step.Cmd) > 0 {
+			// like a k8s init container command, the step command replaces the image entrypoint
+			stepConfig.Entrypoint = strslice.StrSlice{""}
+		}
+
+		stepHostConfig := *hostConfig
+		stepHostConfig.PortBindings = nil
+		stepHostConfig.RestartPolicy = container.RestartPolicy{Name: "no"}
+
+		stepName := fmt.Sprintf("%s-init-%d", GetContainerName(env, service.Name), i+1)
+		utils.Logger.Info("running pre-run step %d/%d of service %s", i+1, len(service.PreRun), service.Name)
+		exitCode, output, err := runInitContainer(ctx, cli, stepName, targetNetworkID, &stepConfig, &stepHostConfig, plt)
+		if err != nil {
+			return fmt.Errorf("failed to run pre-run step %d of service %s : %v", i+1, service.Name, err)
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("pre-run step %d of service %s failed with exit code %d : %s", i+1, service.Name, exitCode, output)
+		}
+	}
+
+	return nil
+}
+
+func runInitContainer(ctx context.Context, cli ContainerRuntime, name string, targetNetworkID string, config *container.Config, hostConfig *container.HostConfig, plt *v1.Platform) (int64, string, error) {
+
+	// leftover of an interrupted activation
+	err := cli.ContainerRemove(ctx, name, types.ContainerRemoveOptions{Force: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return 0, "", err
+	}
+
+	networkConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{targetNetworkID: {}},
+	}
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, networkConfig, plt, name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+
+	waitCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return 0, "", err
+	}
+
+	var exitCode int64
+	select {
+	case result := <-waitCh:
+		if result.Error != nil {
+			return 0, "", errors.New(result.Error.Message)
+		}
+		exitCode = result.StatusCode
+	case err := <-errCh:
+		return 0, "", err
+	}
+
+	logs, err := cli.ContainerLogs(ctx, resp.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return exitCode, "", err
+	}
+	defer logs.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil {
+		return exitCode, "", err
+	}
+
+	return exitCode, strings.TrimSpace(output.String()), nil
+}
+
+// runPostRunSteps executes the service post-run steps in order inside the running container
+func runPostRunSteps(ctx context.Context, cli ContainerRuntime, containerID string, service *model.Service) error {
+
+	for i := range service.PostRun {
+		step := &service.PostRun[i]
+
+		cmd := getHookCommand(step)
+		if len(cmd) == 0 {
+			return fmt.Errorf("invalid post-run step %d of service %s : missing command", i+1, service.Name)
+		}
+
+		utils.Logger.Info("running post-run step %d/%d of service %s", i+1, len(service.PostRun), service.Name)
+		exec, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+			Cmd:          cmd,
+			AttachStdout: true,
+			AttachStderr: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run post-run step %d of service %s : %v", i+1, service.Name, err)
+		}
+
+		attach, err := cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+		if err != nil {
+			return fmt.Errorf("failed to run post-run step %d of service %s : %v", i+1, service.Name, err)
+		}
+		var output bytes.Buffer
+		_, err = stdcopy.StdCopy(&output, &output, attach.Reader)
+		attach.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read post-run step %d of service %s output : %v", i+1, service.Name, err)
+		}
+
+		inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect post-run step %d of service %s : %v", i+1, service.Name, err)
+		}
+		if inspect.ExitCode != 0 {
+			return fmt.Errorf("post-run step %d of service %s failed with exit code %d : %s", i+1, service.Name, inspect.ExitCode, strings.TrimSpace(output.String()))
+		}
+	}
+
+	return nil
+}
+
+// getK8sPreRunSteps maps the pod init containers to pre-run steps
+func getK8sPreRunSteps(initContainers []corev1.Container) []model.Command {
+
+	steps := make([]model.Command, 0, len(initContainers))
+	for _, initContainer := range initContainers {
+		step := model.Command{
+			Image: initContainer.Image,
+			Cmd:   initContainer.Command,
+			Args:  initContainer.Args,
+		}
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil
+	}
+	return steps
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	corev1 "k8s.io/api/core/v1"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHookCommand(t *testing.T) {
+
+	cmd := getHookCommand(&model.Command{Cmd: model.ExecCommand{"npm", "run", "migrate"}, Args: []string{"--env", "dev local"}})
+	assert.Equal(t, []string{"npm", "run", "migrate", "--env", "dev local"}, cmd)
+
+	cmd = getHookCommand(&model.Command{Cmd: model.ExecCommand{"./migrate.sh && ./seed.sh"}, Args: []string{"dev local"}, Shell: true})
+	assert.Equal(t, []string{"/bin/sh", "-c", "./migrate.sh && ./seed.sh 'dev local'"}, cmd)
+}
+
+func TestGetK8sPreRunSteps(t *testing.T) {
+
+	steps := getK8sPreRunSteps([]corev1.Container{
+		{Image: "busybox", Command: []string{"sh", "-c"}, Args: []string{"until nc -z db 5432; do sleep 1; done"}},
+		{Image: "migrate/migrate", Args: []string{"up"}},
+	})
+
+	assert.Equal(t, []model.Command{
+		{Image: "busybox", Cmd: model.ExecCommand{"sh", "-c"}, Args: []string{"until nc -z db 5432; do sleep 1; done"}},
+		{Image: "migrate/migrate", Args: []string{"up"}},
+	}, steps)
+
+	assert.Equal(t, []string{"sh", "-c", "until nc -z db 5432; do sleep 1; done"}, getHookCommand(&steps[0]))
+
+	assert.Nil(t, getK8sPreRunSteps(nil))
+}
+
+// configRuntime keeps the config of every created container
+type configRuntime struct {
+	*MemoryRuntime
+	configs []container.Config
+}
+
+func (r *configRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.ContainerCreateCreatedBody, error) {
+	r.configs = append(r.configs, *config)
+	return r.MemoryRuntime.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+func TestRunPreRunSteps(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := &configRuntime{MemoryRuntime: NewMemoryRuntime()}
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS"}
+	service := &model.Service{Name: "api", PreRun: []model.Command{
+		{Cmd: model.ExecCommand{"./migrate.sh"}},
+		{Image: "busybox", Args: []string{"true"}},
+	}}
+	config := &container.Config{Image: "api:dev", User: "app", WorkingDir: "/app"}
+
+	assert.Nil(t, runPreRunSteps(context.Background(), runtime, "testNetwork", env, service, config, &container.HostConfig{}, nil))
+	assert.Len(t, runtime.configs, 2)
+
+	// a step running the service image runs like the service
+	assert.Equal(t, "api:dev", runtime.configs[0].Image)
+	assert.Equal(t, "app", runtime.configs[0].User)
+	assert.Equal(t, "/app", runtime.configs[0].WorkingDir)
+
+	// a step running its own image keeps the image defaults
+	assert.Equal(t, "busybox", runtime.configs[1].Image)
+	assert.Equal(t, "", runtime.configs[1].User)
+	assert.Equal(t, "", runtime.configs[1].WorkingDir)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"main.go/model"
+)
+
+// HostRunner runs the synchronization steps that happen on the host rather than in the container runtime,
+// fetching service sources to build them and copying databases
+type HostRunner interface {
+	BuildGitImage(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, platform string) (string, error)
+	CopyDatabase(service *model.Service, dbType string, dumper DatabaseCopy) error
+}
+
+// localHost fetches sources into the workspace and copies databases for real
+type localHost struct{}
+
+var _ HostRunner = localHost{}
+
+func (localHost) BuildGitImage(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, platform string) (string, error) {
+	srcLocation, err := FetchGitSource(env, service)
+	if err != nil {
+		return "", err
+	}
+	return BuildServiceImage(ctx, cli, env, service, srcLocation, platform)
+}
+
+func (localHost) CopyDatabase(service *model.Service, dbType string, dumper DatabaseCopy) error {
+	return dumper.Copy()
+}
+
+// planHost leaves the host untouched while planning, sources are neither fetched nor read, the fetch is recorded and
+// the image build is planned out of the declared build params only, database copies are only recorded
+type planHost struct {
+	runtime *MemoryRuntime
+}
+
+var _ HostRunner = planHost{}
+
+func (h planHost) BuildGitImage(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, platform string) (string, error) {
+
+	repository, ref, err := getGitParams(service)
+	if err != nil {
+		return "", err
+	}
+	if ref != "" {
+		repository += "@" + ref
+	}
+	h.runtime.Record(fmt.Sprintf("fetch service %s source %s", service.Name, repository))
+
+	options := getImageBuildOptions(env, service, platform)
+	resp, err := cli.ImageBuild(ctx, strings.NewReader(""), options)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service %s image : %v", service.Name, err)
+	}
+	resp.Body.Close()
+	return options.Tags[0], nil
+}
+
+func (h planHost) CopyDatabase(service *model.Service, dbType string, dumper DatabaseCopy) error {
+	h.runtime.Record(fmt.Sprintf("copy %s database into service %s", dbType, service.Name))
+	return nil
+}
+
+// getHost returns the host runner the service was set up with, the local host otherwise
+func (s DockerSynchronizationService) getHost() HostRunner {
+	if s.Host != nil {
+		return s.Host
+	}
+	return localHost{}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// GetPullPolicy resolves the effective pull policy of a service image, the service policy overrides the environment one,
+// when none is set the kubernetes default applies, Always for untagged or latest images and IfNotPresent otherwise
+func GetPullPolicy(env *model.Environment, service *model.Service, imageName string) (string, error) {
+
+	policy := service.PullPolicy
+	if policy == "" {
+		policy = env.PullPolicy
+	}
+
+	switch policy {
+	case model.PULL_ALWAYS, model.PULL_IF_NOT_PRESENT, model.PULL_NEVER:
+		return policy, nil
+	case "":
+		named, err := reference.ParseNormalizedNamed(imageName)
+		if err != nil {
+			return "", fmt.Errorf("invalid image name %s : %v", imageName, err)
+		}
+		if _, digested := named.(reference.Digested); digested {
+			return model.PULL_IF_NOT_PRESENT, nil
+		}
+		tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+		if ok && tagged.Tag() != "latest" {
+			return model.PULL_IF_NOT_PRESENT, nil
+		}
+		return model.PULL_ALWAYS, nil
+	default:
+		return "", fmt.Errorf("unsupported pull policy %s, expected one of %s, %s or %s", policy, model.PULL_ALWAYS, model.PULL_IF_NOT_PRESENT, model.PULL_NEVER)
+	}
+}
+
+func getRegistryAuth(env *model.Environment, service *model.Service) (string, error) {
+
+	authConfig := service.ContainerRegistry
+	if authConfig == nil {
+		authConfig = env.ContainerRegistry
+	}
+	if authConfig == nil {
+		return "", nil
+	}
+
+	encodedJSON, err := json.Marshal(types.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		RegistryToken: authConfig.Token,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encodedJSON), nil
+}
+
+func pullServiceImage(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, imageName string, platform string) error {
+
+	policy, err := GetPullPolicy(env, service, imageName)
+	if err != nil {
+		return fmt.Errorf("failed to pull service %s image : %v", service.Name, err)
+	}
+
+	if policy != model.PULL_ALWAYS {
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, imageName)
+		if err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to inspect service %s image %s : %v", service.Name, imageName, err)
+		}
+		if err == nil {
+			// a local image pulled for another platform can't run with the resolved one
+			localPlatform := getImagePlatform(inspect)
+			if matchesPlatform(localPlatform, platform) {
+				utils.Logger.Info("image %s of service %s is present locally, skipping pull (pull policy %s)", imageName, service.Name, policy)
+				return nil
+			}
+			if policy == model.PULL_NEVER {
+				return fmt.Errorf("image %s of service %s is present locally for platform %s instead of %s and pull policy is %s", imageName, service.Name, localPlatform, platform, policy)
+			}
+			utils.Logger.Info("image %s of service %s is present locally for platform %s, pulling it for platform %s", imageName, service.Name, localPlatform, platform)
+		} else if policy == model.PULL_NEVER {
+			return fmt.Errorf("image %s of service %s is not present locally and pull policy is %s", imageName, service.Name, policy)
+		}
+	}
+
+	auth, err := getRegistryAuth(env, service)
+	if err != nil {
+		return err
+	}
+	pullOptions := types.ImagePullOptions{
+		Platform:     platform,
+		RegistryAuth: auth,
+	}
+
+	utils.Logger.Info("pulling image %s of service %s for platform %s", imageName, service.Name, platform)
+	reader, err := cli.ImagePull(ctx, imageName, pullOptions)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return logPullProgress(reader, service.Name)
+}
+
+// logPullProgress decodes the docker pull json stream into per layer progress log lines,
+// a layer is logged whenever its status changes or its download crosses another quarter
+func logPullProgress(reader io.Reader, serviceName string) error {
+
+	type layerProgress struct {
+		status  string
+		quarter int64
+	}
+	layers := make(map[string]*layerProgress)
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode pull progress of service %s : %v", serviceName, err)
+		}
+
+		if msg.Error != nil {
+			return fmt.Errorf("failed to pull service %s image : %s", serviceName, msg.Error.Message)
+		}
+		if msg.ErrorMessage != "" {
+			return fmt.Errorf("failed to pull service %s image : %s", serviceName, msg.ErrorMessage)
+		}
+
+		if msg.ID == "" {
+			if msg.Status != "" {
+				utils.Logger.Info("[%s] %s", serviceName, msg.Status)
+			}
+			continue
+		}
+
+		layer, ok := layers[msg.ID]
+		if !ok {
+			layer = &layerProgress{}
+			layers[msg.ID] = layer
+		}
+
+		if msg.Progress != nil && msg.Progress.Total > 0 {
+			quarter := msg.Progress.Current * 4 / msg.Progress.Total
+			if msg.Status == layer.status && quarter == layer.quarter {
+				continue
+			}
+			layer.status = msg.Status
+			layer.quarter = quarter
+			utils.Logger.Info("[%s] layer %s : %s %s/%s (%d%%)", serviceName, msg.ID, strings.ToLower(msg.Status),
+				formatBytes(msg.Progress.Current), formatBytes(msg.Progress.Total), msg.Progress.Current*100/msg.Progress.Total)
+			continue
+		}
+
+		if msg.Status != layer.status {
+			layer.status = msg.Status
+			layer.quarter = 0
+			utils.Logger.Info("[%s] layer %s : %s", serviceName, msg.ID, strings.ToLower(msg.Status))
+		}
+	}
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"main.go/model"
+	"main.go/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setTestLogger points the home directory to a temp folder and initializes the perun logger inside it
+func setTestLogger(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	utils.Logger = utils.GetLogger(false, "", "testlog")
+	return home
+}
+
+func TestGetPullPolicy(t *testing.T) {
+
+	env := &model.Environment{Name: "testEnv"}
+	service := &model.Service{Name: "testService"}
+
+	cases := map[string]string{
+		"redis":                      model.PULL_ALWAYS,
+		"redis:latest":               model.PULL_ALWAYS,
+		"redis:7.0":                  model.PULL_IF_NOT_PRESENT,
+		"gcr.io/project/service:1.2": model.PULL_IF_NOT_PRESENT,
+		"redis@sha256:1fe4ef6cb1d2e5bc1f1b5e3f1fbd7e6e8a0c1f0a5b5e9e2c9b4e4c2e6b1b2c3d": model.PULL_IF_NOT_PRESENT,
+	}
+	for image, expected := range cases {
+		policy, err := GetPullPolicy(env, service, image)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, policy, image)
+	}
+
+	env.PullPolicy = model.PULL_NEVER
+	policy, err := GetPullPolicy(env, service, "redis")
+	assert.Nil(t, err)
+	assert.Equal(t, model.PULL_NEVER, policy)
+
+	service.PullPolicy = model.PULL_ALWAYS
+	policy, err = GetPullPolicy(env, service, "redis:7.0")
+	assert.Nil(t, err)
+	assert.Equal(t, model.PULL_ALWAYS, policy)
+
+	service.PullPolicy = "Sometimes"
+	_, err = GetPullPolicy(env, service, "redis")
+	assert.EqualError(t, err, "unsupported pull policy Sometimes, expected one of Always, IfNotPresent or Never")
+}
+
+func TestLogPullProgressError(t *testing.T) {
+
+	setTestLogger(t)
+
+	stream := `{"status":"Pulling from library/redis","id":"7.0"}
+{"status":"Downloading","progressDetail":{"current":512,"total":2048},"id":"a1b2c3"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`
+	err := logPullProgress(strings.NewReader(stream), "redis")
+
+	assert.EqualError(t, err, "failed to pull service redis image : manifest unknown")
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// isStartedStatus tells whether the environment or service has containers, running or not
+func isStartedStatus(status string) bool {
+	return status == model.ACTIVE_STATUS || status == model.PAUSED_STATUS || status == model.STOPPED_STATUS
+}
+
+// getTargetContainers returns the containers of the requested service, or of every service when serviceName is empty
+func getTargetContainers(ctx context.Context, cli ContainerRuntime, env *model.Environment, serviceName string) (map[string]types.Container, error) {
+
+	if serviceName != "" {
+		if _, ok := env.Services[serviceName]; !ok {
+			return nil, fmt.Errorf("service %s not found in environment %s/%s", serviceName, env.Workspace, env.Name)
+		}
+	}
+
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]types.Container)
+	for name := range env.Services {
+		if serviceName != "" && name != serviceName {
+			continue
+		}
+		containers := existing[name]
+		if len(containers) == 0 {
+			if serviceName != "" {
+				return nil, fmt.Errorf("service %s of environment %s/%s has no container, activate the environment first", name, env.Workspace, env.Name)
+			}
+			utils.Logger.Warn("service %s of environment %s/%s has no container, skipping it", name, env.Workspace, env.Name)
+			continue
+		}
+		targets[name] = containers[0]
+	}
+
+	return targets, nil
+}
+
+func sortedContainerNames(targets map[string]types.Container) []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getStartOrder returns the environment service names in the order they are started, the perun db first and then the dependency waves
+func getStartOrder(env *model.Environment) ([]string, error) {
+
+	waves, err := GetServiceWaves(env, "perun-db")
+	if err != nil {
+		return nil, fmt.Errorf("invalid service dependencies of environment %s/%s : %v", env.Workspace, env.Name, err)
+	}
+
+	names := make([]string, 0, len(env.Services))
+	if _, ok := env.Services["perun-db"]; ok {
+		names = append(names, "perun-db")
+	}
+	for _, wave := range waves {
+		for _, service := range wave {
+			names = append(names, service.Name)
+		}
+	}
+	return names, nil
+}
+
+// Pause freezes the service containers, or stops them when stop is set, their state is kept so they can be resumed
+func (s DockerSynchronizationService) Pause(env *model.Environment, serviceName string, stop bool) error {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getTargetContainers(ctx, cli, env, serviceName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedContainerNames(targets) {
+		c := targets[name]
+		service := env.Services[name]
+
+		if stop {
+			if c.State == "running" || c.State == "paused" {
+				utils.Logger.Info("stopping container %s", containerName(c))
+				if err := cli.ContainerStop(ctx, c.ID, nil); err != nil {
+					return fmt.Errorf("failed to stop service %s : %v", name, err)
+				}
+			}
+			service.Status = model.STOPPED_STATUS
+			continue
+		}
+
+		switch c.State {
+		case "running":
+			utils.Logger.Info("pausing container %s", containerName(c))
+			if err := cli.ContainerPause(ctx, c.ID); err != nil {
+				return fmt.Errorf("failed to pause service %s : %v", name, err)
+			}
+			service.Status = model.PAUSED_STATUS
+		case "paused":
+			service.Status = model.PAUSED_STATUS
+		default:
+			return fmt.Errorf("failed to pause service %s, its container is %s", name, c.State)
+		}
+	}
+
+	return nil
+}
+
+// Resume unpauses or starts back the service containers following the dependency waves and waits for each to be ready
+func (s DockerSynchronizationService) Resume(env *model.Environment, serviceName string) error {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getTargetContainers(ctx, cli, env, serviceName)
+	if err != nil {
+		return err
+	}
+	order, err := getStartOrder(env)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		c, ok := targets[name]
+		if !ok {
+			continue
+		}
+		service := env.Services[name]
+
+		switch c.State {
+		case "running":
+		case "paused":
+			utils.Logger.Info("unpausing container %s", containerName(c))
+			if err := cli.ContainerUnpause(ctx, c.ID); err != nil {
+				return fmt.Errorf("failed to resume service %s : %v", name, err)
+			}
+		default:
+			utils.Logger.Info("starting container %s", containerName(c))
+			if err := cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
+				return fmt.Errorf("failed to resume service %s : %v", name, err)
+			}
+			if err := WaitForService(ctx, cli, c.ID, service); err != nil {
+				return err
+			}
+		}
+		service.Status = model.ACTIVE_STATUS
+	}
+
+	return nil
+}
+
+// Restart restarts a service container in place and waits for it to be ready
+func (s DockerSynchronizationService) Restart(env *model.Environment, serviceName string) error {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getTargetContainers(ctx, cli, env, serviceName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedContainerNames(targets) {
+		c := targets[name]
+		utils.Logger.Info("restarting container %s", containerName(c))
+		if c.State == "paused" {
+			if err := cli.ContainerUnpause(ctx, c.ID); err != nil {
+				return fmt.Errorf("failed to restart service %s : %v", name, err)
+			}
+		}
+		if err := cli.ContainerRestart(ctx, c.ID, nil); err != nil {
+			return fmt.Errorf("failed to restart service %s : %v", name, err)
+		}
+		if err := WaitForService(ctx, cli, c.ID, env.Services[name]); err != nil {
+			return err
+		}
+		env.Services[name].Status = model.ACTIVE_STATUS
+	}
+
+	return nil
+}
+
+// Recreate replaces the service containers with fresh ones created out of the current spec, the other services are left untouched
+func (s DockerSynchronizationService) Recreate(env *model.Environment, serviceName string) error {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getTargetContainers(ctx, cli, env, serviceName)
+	if err != nil {
+		return err
+	}
+	order, err := getStartOrder(env)
+	if err != nil {
+		return err
+	}
+
+	network, err := findNetwork(ctx, cli, GetNetworkName(env))
+	if err != nil {
+		return err
+	}
+	if network == nil {
+		return fmt.Errorf("network %s of environment %s/%s not found, activate the environment first", GetNetworkName(env), env.Workspace, env.Name)
+	}
+
+	daemonPlatform, err := GetDaemonPlatform(ctx, cli)
+	if err != nil {
+		utils.Logger.Warn("failed to detect docker daemon platform, defaulting to %s : %v", DEFAULT_PLATFORM, err)
+		daemonPlatform = DEFAULT_PLATFORM
+	}
+
+	for _, name := range order {
+		c, ok := targets[name]
+		if !ok {
+			continue
+		}
+		service := env.Services[name]
+
+		image, err := prepareServiceImage(ctx, cli, s.getHost(), env, service, daemonPlatform)
+		if err != nil {
+			return fmt.Errorf("failed to recreate service %s : %v", name, err)
+		}
+
+		utils.Logger.Info("recreating container %s", containerName(c))
+		if err := removeContainer(ctx, cli, c); err != nil {
+			return err
+		}
+		containerID, _, err := loadService(ctx, cli, network.ID, env, service, image, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to recreate service %s : %v", name, err)
+		}
+		if err := WaitForService(ctx, cli, containerID, service); err != nil {
+			return err
+		}
+		if err := runPostRunSteps(ctx, cli, containerID, service); err != nil {
+			return err
+		}
+		if name == "perun-db" {
+			if err := copyDatabase(s.getHost(), env, service); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"main.go/model"
+)
+
+func TestResumeOrder(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	assert.Nil(t, s.Synchronize(env))
+	assert.Nil(t, s.Pause(env, "", true))
+	steps := len(runtime.Plan())
+
+	// api depends on cache, so cache starts first
+	assert.Nil(t, s.Resume(env, ""))
+	assert.Equal(t, []string{
+		"start container testWS-testEnv-cache",
+		"start container testWS-testEnv-api",
+	}, runtime.Plan()[steps:])
+	assert.Equal(t, model.ACTIVE_STATUS, env.Services["api"].Status)
+}
+
+func TestRecreate(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	assert.Nil(t, s.Synchronize(env))
+	steps := len(runtime.Plan())
+
+	// only the target container is replaced, its post-run steps run again
+	assert.Nil(t, s.Recreate(env, "api"))
+	assert.Equal(t, []string{
+		"remove container testWS-testEnv-api",
+		"create container testWS-testEnv-api from image nginx:1.25",
+		"connect container testWS-testEnv-api to network testWS-testEnv",
+		"start container testWS-testEnv-api",
+		"exec nginx -t in container testWS-testEnv-api",
+	}, runtime.Plan()[steps:])
+
+	assert.EqualError(t, s.Recreate(env, "unknown"), "service unknown not found in environment testWS/testEnv")
+}
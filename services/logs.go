@@ -1,29 +1,194 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
-	"os"
-	"time"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"main.go/model"
+	"main.go/utils"
 )
 
-func GetLogs(container_id string, tail bool) error {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+const ALL_LOGS = "all"
+
+// LOG_COLORS are the ansi colors cycled through for the service prefixes, the same order docker compose uses
+var LOG_COLORS = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+type LogsOptions struct {
+	Services []string
+	Follow   bool
+	Since    string
+	Tail     string
+	Color    bool
+}
+
+// GetLogs streams the logs of the environment services, interleaved line by line and prefixed with the service name
+func GetLogs(env *model.Environment, options LogsOptions, stdout io.Writer, stderr io.Writer) error {
+
+	tail := options.Tail
+	if tail == "" {
+		tail = ALL_LOGS
+	}
+	if tail != ALL_LOGS {
+		if n, err := strconv.Atoi(tail); err != nil || n < 0 {
+			return fmt.Errorf("invalid tail %s, expected a positive number of lines or %s", tail, ALL_LOGS)
+		}
+	}
+
+	names := options.Services
+	if len(names) == 0 {
+		for name := range env.Services {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := env.Services[name]; !ok {
+			return fmt.Errorf("service %s not found in environment %s/%s", name, env.Workspace, env.Name)
+		}
+	}
+	sort.Strings(names)
+
+	ctx := context.Background()
+	cli, err := GetDockerClient(env.Workspace)
 	if err != nil {
 		return err
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	reader, err := cli.ContainerLogs(ctx, "container_id", types.ContainerLogsOptions{})
+
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(os.Stdout, reader)
+
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(ServiceErrors)
+	streamed := 0
+	for i, name := range names {
+		containers := existing[name]
+		if len(containers) == 0 {
+			utils.Logger.Warn("service %s of environment %s/%s has no container, skipping its logs", name, env.Workspace, env.Name)
+			continue
+		}
+		streamed++
+
+		prefix := fmt.Sprintf("%-*s | ", width, name)
+		if options.Color {
+			prefix = "\033[" + LOG_COLORS[i%len(LOG_COLORS)] + "m" + prefix + "\033[0m"
+		}
+		out := &prefixWriter{prefix: prefix, out: stdout, lock: &lock}
+		errOut := &prefixWriter{prefix: prefix, out: stderr, lock: &lock}
+
+		c := containers[0]
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := streamContainerLogs(ctx, cli, c.ID, options, tail, out, errOut)
+			out.Flush()
+			errOut.Flush()
+			if err != nil {
+				lock.Lock()
+				errs[name] = err
+				lock.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if streamed == 0 {
+		return fmt.Errorf("no container found for environment %s/%s, activate it first", env.Workspace, env.Name)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func streamContainerLogs(ctx context.Context, cli ContainerRuntime, containerID string, options LogsOptions, tail string, stdout io.Writer, stderr io.Writer) error {
+
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container : %v", err)
+	}
+
+	reader, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+		Since:      options.Since,
+		Tail:       tail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get container logs : %v", err)
+	}
+	defer reader.Close()
+
+	// a tty container stream is raw, otherwise stdout and stderr are multiplexed in the same stream
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
 	if err != nil && err != io.EOF {
-		return err
+		return fmt.Errorf("failed to read container logs : %v", err)
 	}
 	return nil
 }
+
+// prefixWriter writes complete lines prefixed with the service name, the lock is shared between services
+// so lines of different services never interleave
+type prefixWriter struct {
+	prefix  string
+	out     io.Writer
+	lock    *sync.Mutex
+	pending []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+
+	var lines strings.Builder
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		lines.WriteString(w.prefix)
+		lines.Write(w.pending[:i+1])
+		w.pending = w.pending[i+1:]
+	}
+
+	if lines.Len() > 0 {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		if _, err := io.WriteString(w.out, lines.String()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line when it has no trailing line break
+func (w *prefixWriter) Flush() {
+	if len(w.pending) == 0 {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	io.WriteString(w.out, w.prefix+string(w.pending)+"\n")
+	w.pending = nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var lock sync.Mutex
+	writer := &prefixWriter{prefix: "api | ", out: &out, lock: &lock}
+
+	writer.Write([]byte("first line\nsecond "))
+	assert.Equal(t, "api | first line\n", out.String())
+
+	writer.Write([]byte("line\nlast"))
+	writer.Flush()
+	assert.Equal(t, "api | first line\napi | second line\napi | last\n", out.String())
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"main.go/utils"
+)
+
+// LOG_LEVEL_KEYS are the json keys a structured log line may hold its level in
+var LOG_LEVEL_KEYS = []string{"level", "lvl", "severity", "log.level"}
+
+// MAX_LOG_LINE_SIZE is the size a persisted log line is cut at when searched
+const MAX_LOG_LINE_SIZE = 1024 * 1024
+
+type LogSearchOptions struct {
+	Environments []string
+	Services     []string
+	Pattern      string
+	Since        time.Time
+	Until        time.Time
+	Level        string
+	Fields       map[string]string
+}
+
+// LogRecord is a persisted log line of a service
+type LogRecord struct {
+	Env       string
+	Service   string
+	Timestamp time.Time
+	Stream    string
+	Message   string
+}
+
+// SearchLogs scans the persisted logs of a workspace and returns the matching records ordered by time
+func SearchLogs(workspace string, options LogSearchOptions) ([]*LogRecord, error) {
+
+	var pattern *regexp.Regexp
+	if options.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile(options.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search pattern %s : %v", options.Pattern, err)
+		}
+	}
+
+	dirname, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch home directory : %v", err)
+	}
+	// the service logs live under <ws>/<env>/logs/<service>
+	dirs, err := filepath.Glob(filepath.Join(dirname+utils.WORKSPACES_HOME, workspace, "*", "logs", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*LogRecord, 0)
+	for _, dir := range dirs {
+		service := filepath.Base(dir)
+		env := filepath.Base(filepath.Dir(filepath.Dir(dir)))
+		if !matchesAny(env, options.Environments) || !matchesAny(service, options.Services) {
+			continue
+		}
+
+		for _, file := range getLogFiles(dir) {
+			found, err := searchLogFile(file, env, service, pattern, options)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, found...)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
+func matchesAny(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func searchLogFile(path string, env string, service string, pattern *regexp.Regexp, options LogSearchOptions) ([]*LogRecord, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open log file %s : %v", path, err)
+	}
+	defer file.Close()
+
+	records := make([]*LogRecord, 0)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := readLogLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log file %s : %v", path, err)
+		}
+		record := parseLogRecord(line)
+		if record == nil || !matchesLogRecord(record, pattern, options) {
+			continue
+		}
+		record.Env = env
+		record.Service = service
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readLogLine reads the next line of a log file, a line longer than MAX_LOG_LINE_SIZE is truncated rather than failing the search
+func readLogLine(reader *bufio.Reader) (string, error) {
+	line := make([]byte, 0)
+	for {
+		fragment, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if room := MAX_LOG_LINE_SIZE - len(line); room > 0 {
+			if len(fragment) > room {
+				fragment = fragment[:room]
+			}
+			line = append(line, fragment...)
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// parseLogRecord parses a "<timestamp> <stream> <message>" line written by the log collector
+func parseLogRecord(line string) *LogRecord {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil
+	}
+	record := &LogRecord{Timestamp: timestamp, Stream: parts[1]}
+	if len(parts) == 3 {
+		record.Message = parts[2]
+	}
+	return record
+}
+
+func matchesLogRecord(record *LogRecord, pattern *regexp.Regexp, options LogSearchOptions) bool {
+
+	if !options.Since.IsZero() && record.Timestamp.Before(options.Since) {
+		return false
+	}
+	if !options.Until.IsZero() && record.Timestamp.After(options.Until) {
+		return false
+	}
+	if pattern != nil && !pattern.MatchString(record.Message) {
+		return false
+	}
+	if options.Level == "" && len(options.Fields) == 0 {
+		return true
+	}
+
+	// level and field filters only apply to json lines
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(record.Message), &fields); err != nil {
+		return false
+	}
+	if options.Level != "" && !strings.EqualFold(getLogLevel(fields), options.Level) {
+		return false
+	}
+	for key, expected := range options.Fields {
+		value, ok := fields[key]
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func getLogLevel(fields map[string]interface{}) string {
+	for _, key := range LOG_LEVEL_KEYS {
+		if level, ok := fields[key]; ok {
+			return fmt.Sprint(level)
+		}
+	}
+	return ""
+}
+
+// ParseTimeBound parses an absolute timestamp or a duration relative to now, such as 2h
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s, expected a timestamp such as 2022-10-01T15:04:05 or a duration such as 2h", value)
+}
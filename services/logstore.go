@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"main.go/utils"
+)
+
+const LOG_FILE_NAME = "service.log"
+const LOG_FILE_MAX_SIZE = 10 * 1024 * 1024
+const LOG_FILE_MAX_BACKUPS = 5
+
+// GetServiceLogsDirectory returns the folder holding the persisted logs of a service
+func GetServiceLogsDirectory(workspace string, envName string, serviceName string) (string, error) {
+	dirname, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch home directory : %v", err)
+	}
+	return filepath.Join(dirname+utils.WORKSPACES_HOME, workspace, envName, "logs", serviceName), nil
+}
+
+// getLogFiles returns the log file and its rotated backups of a service logs folder, oldest first
+func getLogFiles(dir string) []string {
+	files := make([]string, 0, LOG_FILE_MAX_BACKUPS+1)
+	for i := LOG_FILE_MAX_BACKUPS; i > 0; i-- {
+		files = append(files, filepath.Join(dir, LOG_FILE_NAME+"."+strconv.Itoa(i)))
+	}
+	return append(files, filepath.Join(dir, LOG_FILE_NAME))
+}
+
+// rotatingFile appends to a log file, rolling it over to numbered backups once it reaches maxSize
+type rotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create logs folder : %v", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s : %v", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+
+	os.Remove(f.path + "." + strconv.Itoa(f.maxBackups))
+	for i := f.maxBackups - 1; i > 0; i-- {
+		os.Rename(f.path+"."+strconv.Itoa(i), f.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file %s : %v", f.path, err)
+	}
+	return f.open()
+}
+
+// storeWriter turns docker timestamped log lines into "<timestamp> <stream> <message>" records
+type storeWriter struct {
+	stream  string
+	out     io.Writer
+	pending []byte
+}
+
+func (w *storeWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.pending[:i]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *storeWriter) writeLine(line []byte) error {
+	timestamp, message := line, []byte{}
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		timestamp, message = line[:i], line[i+1:]
+	}
+	_, err := fmt.Fprintf(w.out, "%s %s %s\n", timestamp, w.stream, message)
+	return err
+}
+
+func (w *storeWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.writeLine(w.pending)
+		w.pending = nil
+	}
+}
+
+// LogCollector persists the logs of every perun container in the service logs folder, so they outlive the containers
+type LogCollector struct {
+	lock      sync.Mutex
+	capturing map[string]bool
+	files     map[string]*rotatingFile
+}
+
+func NewLogCollector() *LogCollector {
+	return &LogCollector{
+		capturing: make(map[string]bool),
+		files:     make(map[string]*rotatingFile),
+	}
+}
+
+// Run captures the running perun containers logs, then the logs of every perun container started afterwards
+func (lc *LogCollector) Run(ctx context.Context, cli ContainerRuntime) error {
+
+	msgChannel, errs := cli.Events(ctx, types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("event", "start"),
+			filters.Arg("label", "provider=perun"),
+		),
+	})
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", "provider=perun")),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list perun containers : %v", err)
+	}
+	for _, c := range containers {
+		// logs written before the collector went down are already stored
+		go lc.capture(ctx, cli, c.ID, c.Labels, lc.lastWrite(c.Labels))
+	}
+
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case msg := <-msgChannel:
+			go lc.capture(ctx, cli, msg.Actor.ID, msg.Actor.Attributes, time.Unix(0, msg.TimeNano))
+		}
+	}
+}
+
+func (lc *LogCollector) getFile(labels map[string]string) (*rotatingFile, error) {
+	dir, err := GetServiceLogsDirectory(labels["perun-workspace"], labels["perun-env"], labels["perun-service"])
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LOG_FILE_NAME)
+
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	file := lc.files[path]
+	if file == nil {
+		file = &rotatingFile{path: path, maxSize: LOG_FILE_MAX_SIZE, maxBackups: LOG_FILE_MAX_BACKUPS}
+		lc.files[path] = file
+	}
+	return file, nil
+}
+
+// lastWrite returns the time right after the last stored log record of the service, so that a new capture doesn't store it twice,
+// the log file modification time is used when no record can be read back
+func (lc *LogCollector) lastWrite(labels map[string]string) time.Time {
+	dir, err := GetServiceLogsDirectory(labels["perun-workspace"], labels["perun-env"], labels["perun-service"])
+	if err != nil {
+		return time.Time{}
+	}
+	file, err := os.Open(filepath.Join(dir, LOG_FILE_NAME))
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return time.Time{}
+	}
+
+	offset := info.Size() - 64*1024
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return info.ModTime()
+	}
+	lines := strings.Split(strings.TrimRight(string(tail), "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if record := parseLogRecord(lines[i]); record != nil {
+			return record.Timestamp.Add(time.Nanosecond)
+		}
+	}
+	return info.ModTime()
+}
+
+func (lc *LogCollector) capture(ctx context.Context, cli ContainerRuntime, containerID string, labels map[string]string, since time.Time) {
+
+	if labels["perun-workspace"] == "" || labels["perun-env"] == "" || labels["perun-service"] == "" {
+		return
+	}
+
+	lc.lock.Lock()
+	if lc.capturing[containerID] {
+		lc.lock.Unlock()
+		return
+	}
+	lc.capturing[containerID] = true
+	lc.lock.Unlock()
+	defer func() {
+		lc.lock.Lock()
+		delete(lc.capturing, containerID)
+		lc.lock.Unlock()
+	}()
+
+	file, err := lc.getFile(labels)
+	if err != nil {
+		utils.Logger.Error("failed to capture container %s logs : %v", containerID, err)
+		return
+	}
+
+	options := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true}
+	if !since.IsZero() {
+		options.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
+	reader, err := cli.ContainerLogs(ctx, containerID, options)
+	if err != nil {
+		utils.Logger.Error("failed to capture container %s logs : %v", containerID, err)
+		return
+	}
+	defer reader.Close()
+
+	stdout := &storeWriter{stream: "stdout", out: file}
+	stderr := &storeWriter{stream: "stderr", out: file}
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err == nil && inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
+	stdout.Flush()
+	stderr.Flush()
+	if err != nil && err != io.EOF {
+		utils.Logger.Error("failed to capture container %s logs : %v", containerID, err)
+	}
+}
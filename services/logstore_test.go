@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreWriter(t *testing.T) {
+	var out bytes.Buffer
+	writer := &storeWriter{stream: "stderr", out: &out}
+
+	writer.Write([]byte("2022-10-01T15:04:05.000000001Z boom\n2022-10-01T15:04:06Z par"))
+	writer.Write([]byte("tial"))
+	writer.Flush()
+
+	assert.Equal(t, "2022-10-01T15:04:05.000000001Z stderr boom\n2022-10-01T15:04:06Z stderr partial\n", out.String())
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api", LOG_FILE_NAME)
+	file := &rotatingFile{path: path, maxSize: 10, maxBackups: 2}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := file.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	file.file.Close()
+
+	current, _ := os.ReadFile(path)
+	backup, _ := os.ReadFile(path + ".1")
+	oldest, _ := os.ReadFile(path + ".2")
+	assert.Equal(t, "fourth\n", string(current))
+	assert.Equal(t, "third\n", string(backup))
+	assert.Equal(t, "second\n", string(oldest))
+	_, err := os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMatchesLogRecord(t *testing.T) {
+	record := parseLogRecord(`2022-10-01T15:04:05Z stdout {"level":"ERROR","user":"42","msg":"payment failed"}`)
+	assert.NotNil(t, record)
+	assert.Equal(t, "stdout", record.Stream)
+
+	assert.True(t, matchesLogRecord(record, regexp.MustCompile("payment"), LogSearchOptions{}))
+	assert.False(t, matchesLogRecord(record, regexp.MustCompile("refund"), LogSearchOptions{}))
+	assert.True(t, matchesLogRecord(record, nil, LogSearchOptions{Level: "error", Fields: map[string]string{"user": "42"}}))
+	assert.False(t, matchesLogRecord(record, nil, LogSearchOptions{Fields: map[string]string{"user": "7"}}))
+	assert.False(t, matchesLogRecord(record, nil, LogSearchOptions{Since: record.Timestamp.Add(time.Second)}))
+
+	plain := parseLogRecord("2022-10-01T15:04:05Z stdout ERROR payment failed")
+	assert.False(t, matchesLogRecord(plain, nil, LogSearchOptions{Level: "error"}))
+
+	assert.Nil(t, parseLogRecord("not a record"))
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2022, 10, 1, 15, 0, 0, 0, time.UTC)
+
+	since, err := ParseTimeBound("2h", now)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), since)
+
+	since, err = ParseTimeBound("2022-10-01T10:00:00Z", now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC), since.UTC())
+
+	_, err = ParseTimeBound("yesterday", now)
+	assert.NotNil(t, err)
+}
+
+func TestSearchLogFileLongLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LOG_FILE_NAME)
+	content := "2022-10-01T15:04:05Z stdout " + strings.Repeat("x", 2*MAX_LOG_LINE_SIZE) + "\n2022-10-01T15:04:06Z stdout done\n"
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+
+	// the long line is cut instead of aborting the search
+	records, err := searchLogFile(path, "testEnv", "api", nil, LogSearchOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Len(t, records[0].Message, MAX_LOG_LINE_SIZE-len("2022-10-01T15:04:05Z stdout "))
+	assert.Equal(t, "done", records[1].Message)
+}
+
+func TestLastWrite(t *testing.T) {
+	setTestLogger(t)
+
+	labels := map[string]string{"perun-workspace": "testWS", "perun-env": "testEnv", "perun-service": "api"}
+	collector := NewLogCollector()
+	assert.True(t, collector.lastWrite(labels).IsZero())
+
+	dir, err := GetServiceLogsDirectory("testWS", "testEnv", "api")
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(dir, os.ModePerm))
+	content := "2022-10-01T15:04:05.000000001Z stdout first\n2022-10-01T15:04:05.500000000Z stderr last\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, LOG_FILE_NAME), []byte(content), 0644))
+
+	// the capture resumes right after the last stored record, within the same second
+	assert.Equal(t, time.Date(2022, 10, 1, 15, 4, 5, 500000001, time.UTC), collector.lastWrite(labels).UTC())
+}
@@ -0,0 +1,664 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type memoryContainer struct {
+	types.Container
+	config     *container.Config
+	hostConfig *container.HostConfig
+}
+
+// MemoryRuntime keeps networks, volumes, images and containers in memory and records every change it is asked for,
+// containers start right away and commands exit successfully, which makes it a dry-run plan of a synchronization
+type MemoryRuntime struct {
+	lock       sync.Mutex
+	sequence   int
+	source     ContainerRuntime
+	info       types.Info
+	networks   map[string]*types.NetworkResource
+	volumes    map[string]*types.Volume
+	images     map[string]*types.ImageInspect
+	containers map[string]*memoryContainer
+	execs      map[string]string
+	plan       []string
+	host       string
+}
+
+var _ ContainerRuntime = &MemoryRuntime{}
+
+func NewMemoryRuntime() *MemoryRuntime {
+	return &MemoryRuntime{
+		info:       types.Info{OSType: "linux", Architecture: "x86_64"},
+		networks:   make(map[string]*types.NetworkResource),
+		volumes:    make(map[string]*types.Volume),
+		images:     make(map[string]*types.ImageInspect),
+		containers: make(map[string]*memoryContainer),
+		execs:      make(map[string]string),
+		plan:       make([]string, 0),
+		host:       client.DefaultDockerHost,
+	}
+}
+
+// NewMemoryRuntimeFrom returns a memory runtime holding a copy of the source runtime networks, volumes, images and
+// containers, registries and the daemon info are still read from the source, changes are only recorded
+func NewMemoryRuntimeFrom(ctx context.Context, source ContainerRuntime) (*MemoryRuntime, error) {
+
+	r := NewMemoryRuntime()
+	r.source = source
+	r.host = source.DaemonHost()
+
+	info, err := source.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve docker daemon info : %v", err)
+	}
+	r.info = info
+
+	networks, err := source.NetworkList(ctx, types.NetworkListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks : %v", err)
+	}
+	for i := range networks {
+		n := networks[i]
+		n.Containers = make(map[string]types.EndpointResource)
+		r.networks[n.ID] = &n
+	}
+
+	volumes, err := source.VolumeList(ctx, filters.NewArgs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes : %v", err)
+	}
+	for _, v := range volumes.Volumes {
+		volume := *v
+		r.volumes[volume.Name] = &volume
+	}
+
+	images, err := source.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images : %v", err)
+	}
+	for _, image := range images {
+		inspect, _, err := source.ImageInspectWithRaw(ctx, image.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect image %s : %v", image.ID, err)
+		}
+		if inspect.Config == nil {
+			inspect.Config = &container.Config{}
+		}
+		// dangling images are only known by their ID
+		if len(image.RepoTags) == 0 {
+			r.images[inspect.ID] = &inspect
+		}
+		for _, tag := range image.RepoTags {
+			r.images[tag] = &inspect
+		}
+	}
+
+	containers, err := source.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers : %v", err)
+	}
+	for _, c := range containers {
+		copied := &memoryContainer{Container: c}
+		if c.Labels["provider"] == "perun" {
+			inspect, err := source.ContainerInspect(ctx, c.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect container %s : %v", containerName(c), err)
+			}
+			copied.config = inspect.Config
+			if inspect.ContainerJSONBase != nil {
+				copied.hostConfig = inspect.HostConfig
+			}
+		}
+		r.containers[c.ID] = copied
+		for _, n := range r.networks {
+			if c.NetworkSettings != nil {
+				if _, ok := c.NetworkSettings.Networks[n.Name]; ok {
+					n.Containers[c.ID] = types.EndpointResource{Name: containerName(c)}
+				}
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Plan returns the recorded steps in the order they were requested
+func (r *MemoryRuntime) Plan() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.plan...)
+}
+
+func (r *MemoryRuntime) Record(step string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.record(step)
+}
+
+func (r *MemoryRuntime) record(step string) {
+	r.plan = append(r.plan, step)
+}
+
+func (r *MemoryRuntime) newID(kind string) string {
+	r.sequence++
+	return fmt.Sprintf("%s-%d", kind, r.sequence)
+}
+
+func (r *MemoryRuntime) findContainer(nameOrID string) (*memoryContainer, error) {
+	if c, ok := r.containers[nameOrID]; ok {
+		return c, nil
+	}
+	for _, c := range r.containers {
+		if containerName(c.Container) == strings.TrimPrefix(nameOrID, "/") {
+			return c, nil
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("no such container: %s", nameOrID))
+}
+
+func (r *MemoryRuntime) findNetwork(nameOrID string) (*types.NetworkResource, error) {
+	if n, ok := r.networks[nameOrID]; ok {
+		return n, nil
+	}
+	for _, n := range r.networks {
+		if n.Name == nameOrID {
+			return n, nil
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("network %s not found", nameOrID))
+}
+
+// DaemonHost returns the host of the daemon the runtime stands for, the local daemon unless copied from another runtime
+func (r *MemoryRuntime) DaemonHost() string {
+	return r.host
+}
+
+func (r *MemoryRuntime) Info(ctx context.Context) (types.Info, error) {
+	return r.info, nil
+}
+
+func (r *MemoryRuntime) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, err := r.findNetwork(name); err == nil && options.CheckDuplicate {
+		return types.NetworkCreateResponse{}, errdefs.Conflict(fmt.Errorf("network with name %s already exists", name))
+	}
+	id := r.newID("network")
+	r.networks[id] = &types.NetworkResource{
+		ID:         id,
+		Name:       name,
+		Created:    time.Now(),
+		Attachable: options.Attachable,
+		Labels:     options.Labels,
+		Containers: make(map[string]types.EndpointResource),
+	}
+	r.record("create network " + name)
+	return types.NetworkCreateResponse{ID: id}, nil
+}
+
+func (r *MemoryRuntime) NetworkInspect(ctx context.Context, network string, options types.NetworkInspectOptions) (types.NetworkResource, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, err := r.findNetwork(network)
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	return *n, nil
+}
+
+func (r *MemoryRuntime) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	networks := make([]types.NetworkResource, 0)
+	for _, n := range r.networks {
+		if options.Filters.Contains("name") && !options.Filters.Match("name", n.Name) {
+			continue
+		}
+		if !options.Filters.MatchKVList("label", n.Labels) {
+			continue
+		}
+		networks = append(networks, *n)
+	}
+	return networks, nil
+}
+
+func (r *MemoryRuntime) NetworkRemove(ctx context.Context, network string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, err := r.findNetwork(network)
+	if err != nil {
+		return err
+	}
+	delete(r.networks, n.ID)
+	r.record("remove network " + n.Name)
+	return nil
+}
+
+func (r *MemoryRuntime) NetworkConnect(ctx context.Context, network, container string, config *network.EndpointSettings) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, err := r.findNetwork(network)
+	if err != nil {
+		return err
+	}
+	c, err := r.findContainer(container)
+	if err != nil {
+		return err
+	}
+	n.Containers[c.ID] = types.EndpointResource{Name: containerName(c.Container)}
+	r.record(fmt.Sprintf("connect container %s to network %s", containerName(c.Container), n.Name))
+	return nil
+}
+
+func (r *MemoryRuntime) NetworkDisconnect(ctx context.Context, network, container string, force bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, err := r.findNetwork(network)
+	if err != nil {
+		return err
+	}
+	c, err := r.findContainer(container)
+	if err != nil {
+		return err
+	}
+	delete(n.Containers, c.ID)
+	r.record(fmt.Sprintf("disconnect container %s from network %s", containerName(c.Container), n.Name))
+	return nil
+}
+
+func (r *MemoryRuntime) VolumeCreate(ctx context.Context, options volumetypes.VolumeCreateBody) (types.Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if v, ok := r.volumes[options.Name]; ok {
+		return *v, nil
+	}
+	v := &types.Volume{
+		Name:      options.Name,
+		Driver:    "local",
+		Labels:    options.Labels,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	r.volumes[options.Name] = v
+	r.record("create volume " + options.Name)
+	return *v, nil
+}
+
+func (r *MemoryRuntime) VolumeInspect(ctx context.Context, volumeID string) (types.Volume, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	v, ok := r.volumes[volumeID]
+	if !ok {
+		return types.Volume{}, errdefs.NotFound(fmt.Errorf("no such volume: %s", volumeID))
+	}
+	return *v, nil
+}
+
+func (r *MemoryRuntime) VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumeListOKBody, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	volumes := make([]*types.Volume, 0)
+	for _, v := range r.volumes {
+		if filter.MatchKVList("label", v.Labels) {
+			volume := *v
+			volumes = append(volumes, &volume)
+		}
+	}
+	return volumetypes.VolumeListOKBody{Volumes: volumes}, nil
+}
+
+func (r *MemoryRuntime) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.volumes[volumeID]; !ok {
+		return errdefs.NotFound(fmt.Errorf("no such volume: %s", volumeID))
+	}
+	delete(r.volumes, volumeID)
+	r.record("remove volume " + volumeID)
+	return nil
+}
+
+func (r *MemoryRuntime) addImage(ref string, labels map[string]string, platform string) {
+	plt, _ := ParsePlatform(platform)
+	image := &types.ImageInspect{
+		ID:       "sha256:" + r.newID("image"),
+		RepoTags: []string{ref},
+		Created:  time.Now().Format(time.RFC3339Nano),
+		Config:   &container.Config{Labels: labels},
+	}
+	if plt != nil {
+		image.Os = plt.OS
+		image.Architecture = plt.Architecture
+	}
+	r.images[ref] = image
+}
+
+func (r *MemoryRuntime) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.addImage(ref, nil, options.Platform)
+	r.record(fmt.Sprintf("pull image %s for platform %s", ref, options.Platform))
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (r *MemoryRuntime) ImageBuild(ctx context.Context, context io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tag := range options.Tags {
+		r.addImage(tag, options.Labels, options.Platform)
+		r.record(fmt.Sprintf("build image %s for platform %s", tag, options.Platform))
+	}
+	return types.ImageBuildResponse{Body: io.NopCloser(strings.NewReader("")), OSType: "linux"}, nil
+}
+
+func (r *MemoryRuntime) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if inspect, ok := r.images[image]; ok {
+		return *inspect, nil, nil
+	}
+	// like docker, an untagged reference stands for the latest tag
+	if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") && !strings.Contains(image, "@") {
+		if inspect, ok := r.images[image+":latest"]; ok {
+			return *inspect, nil, nil
+		}
+	}
+	for _, inspect := range r.images {
+		if inspect.ID == image {
+			return *inspect, nil, nil
+		}
+	}
+	return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("no such image: %s", image))
+}
+
+func (r *MemoryRuntime) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	images := make([]types.ImageSummary, 0)
+	for _, image := range r.images {
+		if !options.Filters.MatchKVList("label", image.Config.Labels) {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339Nano, image.Created)
+		images = append(images, types.ImageSummary{ID: image.ID, RepoTags: image.RepoTags, Labels: image.Config.Labels, Created: created.Unix()})
+	}
+	return images, nil
+}
+
+func (r *MemoryRuntime) ImageRemove(ctx context.Context, image string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	removed := make([]types.ImageDeleteResponseItem, 0)
+	for ref, inspect := range r.images {
+		if ref == image || inspect.ID == image {
+			delete(r.images, ref)
+			removed = append(removed, types.ImageDeleteResponseItem{Untagged: ref})
+			r.record("remove image " + ref)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, errdefs.NotFound(fmt.Errorf("no such image: %s", image))
+	}
+	return removed, nil
+}
+
+// DistributionInspect asks the registry through the source runtime, without one the daemon platform is used
+func (r *MemoryRuntime) DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	if r.source != nil {
+		return r.source.DistributionInspect(ctx, image, encodedRegistryAuth)
+	}
+	return registry.DistributionInspect{}, nil
+}
+
+func (r *MemoryRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.ContainerCreateCreatedBody, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, err := r.findContainer(containerName); err == nil {
+		return container.ContainerCreateCreatedBody{}, errdefs.Conflict(fmt.Errorf("container name %s is already in use", containerName))
+	}
+
+	id := r.newID("container")
+	c := &memoryContainer{
+		Container: types.Container{
+			ID:      id,
+			Names:   []string{"/" + containerName},
+			Image:   config.Image,
+			Labels:  config.Labels,
+			Created: time.Now().Unix(),
+			State:   "created",
+		},
+		config:     config,
+		hostConfig: hostConfig,
+	}
+	if image, ok := r.images[config.Image]; ok {
+		c.ImageID = image.ID
+	}
+	for port, bindings := range hostConfig.PortBindings {
+		for _, binding := range bindings {
+			var publicPort int
+			fmt.Sscanf(binding.HostPort, "%d", &publicPort)
+			c.Ports = append(c.Ports, types.Port{PrivatePort: uint16(port.Int()), PublicPort: uint16(publicPort), Type: port.Proto()})
+		}
+	}
+	r.containers[id] = c
+	r.record(fmt.Sprintf("create container %s from image %s", containerName, config.Image))
+
+	if networkingConfig != nil {
+		for networkID := range networkingConfig.EndpointsConfig {
+			if n, err := r.findNetwork(networkID); err == nil {
+				n.Containers[id] = types.EndpointResource{Name: containerName}
+			}
+		}
+	}
+	return container.ContainerCreateCreatedBody{ID: id}, nil
+}
+
+// setContainerState moves a container to the given state and records the change under the given action
+func (r *MemoryRuntime) setContainerState(nameOrID string, action string, state string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, err := r.findContainer(nameOrID)
+	if err != nil {
+		return err
+	}
+	c.State = state
+	r.record(fmt.Sprintf("%s container %s", action, containerName(c.Container)))
+	return nil
+}
+
+func (r *MemoryRuntime) ContainerStart(ctx context.Context, container string, options types.ContainerStartOptions) error {
+	return r.setContainerState(container, "start", "running")
+}
+
+func (r *MemoryRuntime) ContainerStop(ctx context.Context, container string, timeout *time.Duration) error {
+	return r.setContainerState(container, "stop", "exited")
+}
+
+func (r *MemoryRuntime) ContainerRestart(ctx context.Context, container string, timeout *time.Duration) error {
+	return r.setContainerState(container, "restart", "running")
+}
+
+func (r *MemoryRuntime) ContainerPause(ctx context.Context, container string) error {
+	return r.setContainerState(container, "pause", "paused")
+}
+
+func (r *MemoryRuntime) ContainerUnpause(ctx context.Context, container string) error {
+	return r.setContainerState(container, "unpause", "running")
+}
+
+func (r *MemoryRuntime) ContainerRemove(ctx context.Context, container string, options types.ContainerRemoveOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, err := r.findContainer(container)
+	if err != nil {
+		return err
+	}
+	delete(r.containers, c.ID)
+	for _, n := range r.networks {
+		delete(n.Containers, c.ID)
+	}
+	r.record("remove container " + containerName(c.Container))
+	return nil
+}
+
+func (r *MemoryRuntime) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	containers := make([]types.Container, 0)
+	for _, c := range r.containers {
+		if !options.All && c.State != "running" {
+			continue
+		}
+		if options.Filters.Contains("name") && !options.Filters.Match("name", containerName(c.Container)) {
+			continue
+		}
+		if !options.Filters.MatchKVList("label", c.Labels) {
+			continue
+		}
+		containers = append(containers, c.Container)
+	}
+	return containers, nil
+}
+
+func (r *MemoryRuntime) ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, err := r.findContainer(container)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	state := &types.ContainerState{
+		Status:  c.State,
+		Running: c.State == "running" || c.State == "paused",
+		Paused:  c.State == "paused",
+	}
+	if state.Running {
+		state.StartedAt = time.Unix(c.Created, 0).Format(time.RFC3339Nano)
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.ID,
+			Name:       c.Names[0],
+			Image:      c.ImageID,
+			State:      state,
+			HostConfig: c.hostConfig,
+		},
+		Config: c.config,
+	}, nil
+}
+
+// ContainerWait reports every container as exited successfully
+func (r *MemoryRuntime) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error) {
+	results := make(chan container.ContainerWaitOKBody, 1)
+	errs := make(chan error, 1)
+	results <- container.ContainerWaitOKBody{StatusCode: 0}
+	return results, errs
+}
+
+func (r *MemoryRuntime) ContainerStats(ctx context.Context, container string, stream bool) (types.ContainerStats, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, err := r.findContainer(container); err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: io.NopCloser(strings.NewReader("{}")), OSType: "linux"}, nil
+}
+
+func (r *MemoryRuntime) ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, err := r.findContainer(container)
+	if err != nil {
+		return types.IDResponse{}, err
+	}
+	id := r.newID("exec")
+	r.execs[id] = c.ID
+	r.record(fmt.Sprintf("exec %s in container %s", strings.Join(config.Cmd, " "), containerName(c.Container)))
+	return types.IDResponse{ID: id}, nil
+}
+
+// ContainerExecAttach returns a connection without any output, the command is done as soon as it is attached to
+func (r *MemoryRuntime) ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.execs[execID]; !ok {
+		return types.HijackedResponse{}, errdefs.NotFound(fmt.Errorf("no such exec instance: %s", execID))
+	}
+	conn, remote := net.Pipe()
+	remote.Close()
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(strings.NewReader(""))}, nil
+}
+
+func (r *MemoryRuntime) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	containerID, ok := r.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, errdefs.NotFound(fmt.Errorf("no such exec instance: %s", execID))
+	}
+	return types.ContainerExecInspect{ExecID: execID, ContainerID: containerID, ExitCode: 0}, nil
+}
+
+func (r *MemoryRuntime) ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, err := r.findContainer(container); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// Events never reports any event, the context error is sent once the context is done
+func (r *MemoryRuntime) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	messages := make(chan events.Message)
+	errs := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		errs <- ctx.Err()
+	}()
+	return messages, errs
+}
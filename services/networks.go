@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// GetNetworkName returns the docker network of the environment, each environment gets its own network unless it opts into
+// the workspace shared network, where services of other environments are reachable by their <workspace>-<env>-<service> container name
+func GetNetworkName(env *model.Environment) string {
+	if env.SharedNetwork {
+		return env.Workspace
+	}
+	return env.Workspace + "-" + env.Name
+}
+
+// findNetwork looks a network up by its exact name, docker name filter being a substring match
+func findNetwork(ctx context.Context, cli ContainerRuntime, name string) (*types.NetworkResource, error) {
+
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", name)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks : %v", err)
+	}
+
+	var found *types.NetworkResource
+	for i, n := range networks {
+		if n.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("too many networks with name %s", name)
+		}
+		found = &networks[i]
+	}
+
+	return found, nil
+}
+
+// ensureNetwork returns the ID of the environment network, creating it when missing
+func ensureNetwork(ctx context.Context, cli ContainerRuntime, env *model.Environment, journal *activationJournal) (string, error) {
+
+	name := GetNetworkName(env)
+	existing, err := findNetwork(ctx, cli, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return existing.ID, nil
+	}
+
+	labels := map[string]string{"provider": "perun", "perun-workspace": env.Workspace}
+	if !env.SharedNetwork {
+		labels["perun-env"] = env.Name
+	}
+
+	utils.Logger.Info("creating network %s", name)
+	resp, err := cli.NetworkCreate(ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Attachable:     true,
+		Labels:         labels,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create network %s : %v", name, err)
+	}
+	journal.record(NETWORK_RESOURCE, resp.ID, name)
+
+	return resp.ID, nil
+}
+
+// checkNetworkAliases fails when a service of the environment would share its network alias with a service of another
+// environment attached to the same shared network, docker would then resolve the alias to either container
+func checkNetworkAliases(ctx context.Context, cli ContainerRuntime, env *model.Environment) error {
+
+	if !env.SharedNetwork {
+		return nil
+	}
+
+	name := GetNetworkName(env)
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "perun-network="+name)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list network %s containers : %v", name, err)
+	}
+
+	for _, c := range containers {
+		otherEnv := c.Labels["perun-env"]
+		serviceName := c.Labels["perun-service"]
+		if otherEnv == env.Name {
+			continue
+		}
+		if _, found := env.Services[serviceName]; found {
+			return fmt.Errorf("service %s is already reachable on shared network %s as a service of env %s, rename one of them or turn off shared_network", serviceName, name, otherEnv)
+		}
+	}
+
+	return nil
+}
+
+// releaseNetwork removes the environment network once no container is attached to it anymore,
+// a shared network stays up as long as another environment of the workspace uses it
+func releaseNetwork(ctx context.Context, cli ContainerRuntime, env *model.Environment) error {
+
+	name := GetNetworkName(env)
+	existing, err := findNetwork(ctx, cli, name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	// stopped containers are detached from the network until they start again, so users are found by label
+	users, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "perun-network="+name)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list network %s containers : %v", name, err)
+	}
+	if len(users) > 0 {
+		utils.Logger.Info("network %s is still used by %d containers, keeping it", name, len(users))
+		return nil
+	}
+
+	utils.Logger.Info("removing network %s", name)
+	if err := cli.NetworkRemove(ctx, existing.ID); err != nil && !client.IsErrNotFound(err) {
+		return fmt.Errorf("failed to remove network %s : %v", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNetworkName(t *testing.T) {
+
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS"}
+	assert.Equal(t, "testWS-testEnv", GetNetworkName(env))
+
+	env.SharedNetwork = true
+	assert.Equal(t, "testWS", GetNetworkName(env))
+}
+
+func TestSharedNetwork(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+	ctx := context.Background()
+
+	first := &model.Environment{Name: "first", Workspace: "testWS", SharedNetwork: true, Services: map[string]*model.Service{
+		"api": {Name: "api", Type: "docker", Params: map[string]string{"image": "nginx:1.25"}, Run: &model.RunConfig{}},
+	}}
+	second := &model.Environment{Name: "second", Workspace: "testWS", SharedNetwork: true, Services: map[string]*model.Service{
+		"api": {Name: "api", Type: "docker", Params: map[string]string{"image": "nginx:1.25"}, Run: &model.RunConfig{}},
+	}}
+	assert.Nil(t, s.Synchronize(first))
+
+	// both api services would answer to the api alias of the shared network
+	err := s.Synchronize(second)
+	assert.EqualError(t, err, "failed to synchronize env second : service api is already reachable on shared network testWS as a service of env first, rename one of them or turn off shared_network")
+
+	second.Services = map[string]*model.Service{
+		"web": {Name: "web", Type: "docker", Params: map[string]string{"image": "nginx:1.25"}, Run: &model.RunConfig{}},
+	}
+	assert.Nil(t, s.Synchronize(second))
+
+	// a stopped container of the first env still uses the shared network
+	assert.Nil(t, runtime.ContainerStop(ctx, GetContainerName(first, "api"), nil))
+	assert.Nil(t, s.Destroy(second))
+	network, err := findNetwork(ctx, runtime, "testWS")
+	assert.Nil(t, err)
+	assert.NotNil(t, network)
+
+	assert.Nil(t, s.Destroy(first))
+	network, err = findNetwork(ctx, runtime, "testWS")
+	assert.Nil(t, err)
+	assert.Nil(t, network)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const DEFAULT_PLATFORM = "linux/amd64"
+
+// GetPlatformOverride returns the platform explicitly requested for the service, the service setting wins over the environment one
+func GetPlatformOverride(env *model.Environment, service *model.Service) string {
+	if service.Platform != "" {
+		return service.Platform
+	}
+	return env.Platform
+}
+
+// ParsePlatform parses an os/arch[/variant] platform string
+func ParsePlatform(platform string) (*v1.Platform, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid platform %s, expected os/arch[/variant]", platform)
+	}
+	plt := &v1.Platform{
+		OS:           parts[0],
+		Architecture: parts[1],
+	}
+	if len(parts) == 3 {
+		plt.Variant = parts[2]
+	}
+	return plt, nil
+}
+
+// getImagePlatform returns the platform of a local image, empty when the daemon doesn't report it
+func getImagePlatform(inspect types.ImageInspect) string {
+	if inspect.Architecture == "" {
+		return ""
+	}
+	os := inspect.Os
+	if os == "" {
+		os = "linux"
+	}
+	return formatPlatform(v1.Platform{OS: os, Architecture: inspect.Architecture, Variant: inspect.Variant})
+}
+
+// matchesPlatform tells whether an image platform can run as the wanted one, a variant only matters when both sides set it
+func matchesPlatform(platform string, wanted string) bool {
+	if platform == "" || wanted == "" {
+		return true
+	}
+	plt, err := ParsePlatform(platform)
+	if err != nil {
+		return false
+	}
+	wantedPlt, err := ParsePlatform(wanted)
+	if err != nil {
+		return false
+	}
+	return plt.OS == wantedPlt.OS && plt.Architecture == wantedPlt.Architecture &&
+		(plt.Variant == "" || wantedPlt.Variant == "" || plt.Variant == wantedPlt.Variant)
+}
+
+func formatPlatform(plt v1.Platform) string {
+	platform := plt.OS + "/" + plt.Architecture
+	if plt.Variant != "" {
+		platform += "/" + plt.Variant
+	}
+	return platform
+}
+
+// GetDaemonPlatform returns the native platform of the docker daemon
+func GetDaemonPlatform(ctx context.Context, cli ContainerRuntime) (string, error) {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve docker daemon info : %v", err)
+	}
+
+	osType := info.OSType
+	if osType == "" {
+		osType = "linux"
+	}
+
+	switch info.Architecture {
+	case "x86_64", "amd64":
+		return osType + "/amd64", nil
+	case "aarch64", "arm64":
+		return osType + "/arm64", nil
+	case "armv7l", "armhf":
+		return osType + "/arm/v7", nil
+	case "armv6l":
+		return osType + "/arm/v6", nil
+	case "i386", "i686":
+		return osType + "/386", nil
+	case "":
+		return DEFAULT_PLATFORM, nil
+	default:
+		return osType + "/" + info.Architecture, nil
+	}
+}
+
+// resolveImagePlatform picks the platform to pull and run the service image with, an explicit override is used as is,
+// a local image that won't be pulled again keeps its own platform, otherwise the daemon platform is used whenever
+// the image provides a matching manifest, the registry is only queried when a pull is going to happen
+func resolveImagePlatform(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, imageName string, daemonPlatform string) (string, error) {
+
+	if override := GetPlatformOverride(env, service); override != "" {
+		if _, err := ParsePlatform(override); err != nil {
+			return "", err
+		}
+		return override, nil
+	}
+
+	policy, err := GetPullPolicy(env, service, imageName)
+	if err != nil {
+		return "", err
+	}
+	if policy != model.PULL_ALWAYS {
+		inspect, _, err := cli.ImageInspectWithRaw(ctx, imageName)
+		if err == nil {
+			localPlatform := getImagePlatform(inspect)
+			if matchesPlatform(localPlatform, daemonPlatform) {
+				return daemonPlatform, nil
+			}
+			if policy == model.PULL_NEVER {
+				utils.Logger.Warn("image %s of service %s is present locally for platform %s only, it will run under emulation", imageName, service.Name, localPlatform)
+				return localPlatform, nil
+			}
+		} else if policy == model.PULL_NEVER {
+			// nothing will be pulled, the missing image is reported by the pull itself
+			return daemonPlatform, nil
+		}
+	}
+
+	auth, err := getRegistryAuth(env, service)
+	if err != nil {
+		return "", err
+	}
+
+	distribution, err := cli.DistributionInspect(ctx, imageName, auth)
+	if err != nil || len(distribution.Platforms) == 0 {
+		// registry unreachable or a local only image, let the daemon pick
+		return daemonPlatform, nil
+	}
+
+	if _, err := ParsePlatform(daemonPlatform); err != nil {
+		return "", err
+	}
+	for _, plt := range distribution.Platforms {
+		if matchesPlatform(formatPlatform(plt), daemonPlatform) {
+			return daemonPlatform, nil
+		}
+	}
+
+	available := make([]string, 0, len(distribution.Platforms))
+	for _, plt := range distribution.Platforms {
+		available = append(available, formatPlatform(plt))
+	}
+	utils.Logger.Warn("image %s of service %s has no %s manifest (available : %s), it will run under emulation", imageName, service.Name, daemonPlatform, strings.Join(available, ", "))
+	return formatPlatform(distribution.Platforms[0]), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"main.go/model"
+
+	"github.com/docker/docker/api/types/registry"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatform(t *testing.T) {
+
+	plt, err := ParsePlatform("linux/arm64")
+	assert.Nil(t, err)
+	assert.Equal(t, &v1.Platform{OS: "linux", Architecture: "arm64"}, plt)
+
+	plt, err = ParsePlatform("linux/arm/v7")
+	assert.Nil(t, err)
+	assert.Equal(t, &v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, plt)
+
+	_, err = ParsePlatform("arm64")
+	assert.EqualError(t, err, "invalid platform arm64, expected os/arch[/variant]")
+}
+
+func TestGetTaskConfigPlatform(t *testing.T) {
+
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS"}
+	service := &model.Service{Name: "testService", Params: map[string]string{}, Run: &model.RunConfig{}}
+	generator := VSCodeConfigGenerator{}
+
+	taskConfig, err := generator.GetTaskConfig(env, service)
+	assert.Nil(t, err)
+	assert.Equal(t, "", taskConfig.Tasks[0].DockerBuild.CustomOptions)
+
+	env.Platform = "linux/amd64"
+	service.Platform = "linux/arm64"
+	taskConfig, err = generator.GetTaskConfig(env, service)
+	assert.Nil(t, err)
+	assert.Equal(t, "--platform linux/arm64", taskConfig.Tasks[0].DockerBuild.CustomOptions)
+}
+
+func TestMatchesPlatform(t *testing.T) {
+
+	assert.True(t, matchesPlatform("linux/arm64", "linux/arm64"))
+	assert.True(t, matchesPlatform("linux/arm64/v8", "linux/arm64"))
+	assert.True(t, matchesPlatform("", "linux/arm64"))
+	assert.False(t, matchesPlatform("linux/amd64", "linux/arm64"))
+	assert.False(t, matchesPlatform("linux/arm/v6", "linux/arm/v7"))
+}
+
+func TestPullServiceImagePlatform(t *testing.T) {
+	setTestLogger(t)
+
+	ctx := context.Background()
+	runtime := NewMemoryRuntime()
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS", PullPolicy: model.PULL_IF_NOT_PRESENT}
+	service := &model.Service{Name: "api"}
+
+	assert.Nil(t, pullServiceImage(ctx, runtime, env, service, "nginx:1.25", "linux/amd64"))
+	assert.Nil(t, pullServiceImage(ctx, runtime, env, service, "nginx:1.25", "linux/amd64"))
+	assert.Len(t, runtime.Plan(), 1)
+
+	// the amd64 image present locally is not reused for arm64
+	assert.Nil(t, pullServiceImage(ctx, runtime, env, service, "nginx:1.25", "linux/arm64"))
+	assert.Equal(t, "pull image nginx:1.25 for platform linux/arm64", runtime.Plan()[1])
+
+	env.PullPolicy = model.PULL_NEVER
+	err := pullServiceImage(ctx, runtime, env, service, "nginx:1.25", "linux/amd64")
+	assert.EqualError(t, err, "image nginx:1.25 of service api is present locally for platform linux/arm64 instead of linux/amd64 and pull policy is Never")
+}
+
+// registryRuntime counts the registry lookups and reports an arm64 only image
+type registryRuntime struct {
+	*MemoryRuntime
+	inspects int
+}
+
+func (r *registryRuntime) DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	r.inspects++
+	return registry.DistributionInspect{Platforms: []v1.Platform{{OS: "linux", Architecture: "arm64"}}}, nil
+}
+
+func TestResolveImagePlatform(t *testing.T) {
+	setTestLogger(t)
+
+	ctx := context.Background()
+	runtime := &registryRuntime{MemoryRuntime: NewMemoryRuntime()}
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS", PullPolicy: model.PULL_IF_NOT_PRESENT}
+	service := &model.Service{Name: "api"}
+
+	// a missing image is going to be pulled, the registry tells its platforms
+	platform, err := resolveImagePlatform(ctx, runtime, env, service, "nginx:1.25", "linux/amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, "linux/arm64", platform)
+	assert.Equal(t, 1, runtime.inspects)
+
+	// a local image is used as is without reaching the registry
+	assert.Nil(t, pullServiceImage(ctx, runtime, env, service, "nginx:1.25", "linux/amd64"))
+	platform, err = resolveImagePlatform(ctx, runtime, env, service, "nginx:1.25", "linux/amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, "linux/amd64", platform)
+
+	env.PullPolicy = model.PULL_NEVER
+	platform, err = resolveImagePlatform(ctx, runtime, env, service, "nginx:1.25", "linux/arm64")
+	assert.Nil(t, err)
+	assert.Equal(t, "linux/amd64", platform)
+	platform, err = resolveImagePlatform(ctx, runtime, env, service, "redis:7", "linux/amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, "linux/amd64", platform)
+	assert.Equal(t, 1, runtime.inspects)
+
+	env.PullPolicy = model.PULL_ALWAYS
+	_, err = resolveImagePlatform(ctx, runtime, env, service, "nginx:1.25", "linux/amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, runtime.inspects)
+}
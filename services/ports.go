@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// AUTO_HOST_PORT lets perun pick a free host port, the chosen port is saved in the workspace and reused on later activations
+const AUTO_HOST_PORT = "auto"
+
+// PortMapping is a published service port
+type PortMapping struct {
+	Service       string
+	HostPort      string
+	ContainerPort string
+}
+
+// GetHostPort returns the host port a published port binds, the port chosen for an auto port or the configured one
+func GetHostPort(port model.Port) string {
+	if port.HostPort == AUTO_HOST_PORT {
+		return port.AssignedHostPort
+	}
+	return port.HostPort
+}
+
+func getPortProtocol(port model.Port) string {
+	if strings.HasSuffix(port.Port, "/udp") {
+		return "udp"
+	}
+	return "tcp"
+}
+
+// isHostPortFree checks whether the host port can be bound
+func isHostPortFree(protocol string, hostPort string) bool {
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", ":"+hostPort)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	listener, err := net.Listen("tcp", ":"+hostPort)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+func getFreeHostPort(protocol string) (string, error) {
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", ":0")
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		return strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port), nil
+	}
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+// hostPorts tells which host ports the docker daemon can still publish, ports published by running containers are taken,
+// the remaining ones are probed on this machine when the daemon runs on it, a remote daemon host can't be probed from here
+type hostPorts struct {
+	local     bool
+	published map[string]bool
+}
+
+func getHostPorts(ctx context.Context, cli ContainerRuntime) (hostPorts, error) {
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return hostPorts{}, fmt.Errorf("failed to list published host ports : %v", err)
+	}
+	ports := hostPorts{local: isLocalDaemon(cli.DaemonHost()), published: make(map[string]bool)}
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 {
+				ports.published[p.Type+"/"+strconv.Itoa(int(p.PublicPort))] = true
+			}
+		}
+	}
+	return ports, nil
+}
+
+func (p hostPorts) isFree(protocol string, hostPort string) bool {
+	if p.published[protocol+"/"+hostPort] {
+		return false
+	}
+	return !p.local || isHostPortFree(protocol, hostPort)
+}
+
+// getFreePort picks a host port neither published nor taken, the kernel picks it for a local daemon,
+// a remote daemon gets the first one of the ephemeral range
+func (p hostPorts) getFreePort(protocol string, taken func(hostPort string) bool) (string, error) {
+	if !p.local {
+		for port := 49152; port <= 65535; port++ {
+			hostPort := strconv.Itoa(port)
+			if !p.published[protocol+"/"+hostPort] && !taken(hostPort) {
+				return hostPort, nil
+			}
+		}
+		return "", fmt.Errorf("no %s host port left", protocol)
+	}
+	for {
+		hostPort, err := getFreeHostPort(protocol)
+		if err != nil {
+			return "", err
+		}
+		if !p.published[protocol+"/"+hostPort] && !taken(hostPort) {
+			return hostPort, nil
+		}
+	}
+}
+
+// allocateHostPorts checks that every requested host port is free on the docker daemon host and picks a port for each
+// auto host port, ports already published by the environment own containers count as free since those containers get
+// reconciled, an auto port without an assignment takes back the host port its container already publishes
+func allocateHostPorts(ctx context.Context, cli ContainerRuntime, env *model.Environment, existing map[string][]types.Container) error {
+
+	ports, err := getHostPorts(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[string]bool)
+	for _, containers := range existing {
+		for _, c := range containers {
+			for _, p := range c.Ports {
+				if p.PublicPort != 0 {
+					owned[p.Type+"/"+strconv.Itoa(int(p.PublicPort))] = true
+				}
+			}
+		}
+	}
+
+	serviceNames := make([]string, 0, len(env.Services))
+	for name := range env.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+
+	requested := make(map[string]string)
+	available := func(protocol string, hostPort string) bool {
+		key := protocol + "/" + hostPort
+		if _, taken := requested[key]; taken {
+			return false
+		}
+		return owned[key] || ports.isFree(protocol, hostPort)
+	}
+
+	// fixed host ports first so auto ports never steal them
+	for _, autoPass := range []bool{false, true} {
+		for _, name := range serviceNames {
+			service := env.Services[name]
+			if service.Run == nil {
+				continue
+			}
+			for i := range service.Run.Ports {
+				port := &service.Run.Ports[i]
+				if !port.Exposed || port.HostPort == "" || (port.HostPort == AUTO_HOST_PORT) != autoPass {
+					continue
+				}
+				protocol := getPortProtocol(*port)
+
+				if port.HostPort != AUTO_HOST_PORT {
+					if _, err := strconv.ParseUint(port.HostPort, 10, 16); err != nil {
+						return fmt.Errorf("invalid host port %s for service %s port %s, expected a port number or %s", port.HostPort, service.Name, port.Port, AUTO_HOST_PORT)
+					}
+					key := protocol + "/" + port.HostPort
+					if owner, taken := requested[key]; taken {
+						return fmt.Errorf("host port %s of service %s is already requested by service %s", port.HostPort, service.Name, owner)
+					}
+					if !available(protocol, port.HostPort) {
+						return fmt.Errorf("host port %s requested by service %s for port %s is already in use, free it or set the service hostport to %s", port.HostPort, service.Name, port.Port, AUTO_HOST_PORT)
+					}
+					requested[key] = service.Name
+					continue
+				}
+
+				if port.AssignedHostPort == "" {
+					port.AssignedHostPort = getPublishedHostPort(existing[service.Name], protocol, *port)
+				}
+				if port.AssignedHostPort == "" || !available(protocol, port.AssignedHostPort) {
+					hostPort, err := ports.getFreePort(protocol, func(hostPort string) bool {
+						_, taken := requested[protocol+"/"+hostPort]
+						return taken
+					})
+					if err != nil {
+						return fmt.Errorf("failed to allocate a host port for service %s port %s : %v", service.Name, port.Port, err)
+					}
+					if port.AssignedHostPort != "" {
+						utils.Logger.Warn("host port %s of service %s is no longer available, moving port %s to host port %s", port.AssignedHostPort, service.Name, port.Port, hostPort)
+					}
+					port.AssignedHostPort = hostPort
+				}
+				requested[protocol+"/"+port.AssignedHostPort] = service.Name
+			}
+		}
+	}
+
+	return nil
+}
+
+// getPublishedHostPort returns the host port a service container publishes for the container port, empty when it publishes none
+func getPublishedHostPort(containers []types.Container, protocol string, port model.Port) string {
+	containerPort := strings.SplitN(port.Port, "/", 2)[0]
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 && p.Type == protocol && strconv.Itoa(int(p.PrivatePort)) == containerPort {
+				return strconv.Itoa(int(p.PublicPort))
+			}
+		}
+	}
+	return ""
+}
+
+// keepAssignedHostPorts carries the host ports chosen for auto ports over from the previous definition of the environment,
+// so re-applying an environment neither moves its ports nor changes the services spec
+func keepAssignedHostPorts(previous *model.Environment, env *model.Environment) {
+	for name, service := range env.Services {
+		previousService := previous.Services[name]
+		if service.Run == nil || previousService == nil || previousService.Run == nil {
+			continue
+		}
+		for i := range service.Run.Ports {
+			port := &service.Run.Ports[i]
+			if port.HostPort != AUTO_HOST_PORT || port.AssignedHostPort != "" {
+				continue
+			}
+			for _, previousPort := range previousService.Run.Ports {
+				if previousPort.Port == port.Port && previousPort.HostPort == AUTO_HOST_PORT {
+					port.AssignedHostPort = previousPort.AssignedHostPort
+					break
+				}
+			}
+		}
+	}
+}
+
+// GetPortMappings lists the published host:container ports of the environment services, sorted by service
+func GetPortMappings(env *model.Environment) []PortMapping {
+
+	mappings := make([]PortMapping, 0)
+	for _, service := range env.Services {
+		if service.Run == nil {
+			continue
+		}
+		for _, port := range service.Run.Ports {
+			if !port.Exposed {
+				continue
+			}
+			hostPort := GetHostPort(port)
+			if hostPort == "" {
+				// docker picks an ephemeral port on each start
+				hostPort = "random"
+			}
+			containerPort := port.Port
+			if !strings.Contains(containerPort, "/") {
+				containerPort += "/tcp"
+			}
+			mappings = append(mappings, PortMapping{
+				Service:       service.Name,
+				HostPort:      hostPort,
+				ContainerPort: containerPort,
+			})
+		}
+	}
+
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Service != mappings[j].Service {
+			return mappings[i].Service < mappings[j].Service
+		}
+		return mappings[i].ContainerPort < mappings[j].ContainerPort
+	})
+	return mappings
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// PruneResource is a docker resource or a local directory left behind by perun
+type PruneResource struct {
+	Kind      string
+	ID        string
+	Name      string
+	Workspace string
+	Env       string
+	Created   time.Time
+	Reason    string
+}
+
+// PruneOptions narrows the prune down to a single workspace and to resources older than a given age
+type PruneOptions struct {
+	Workspace string
+	OlderThan time.Duration
+	DryRun    bool
+}
+
+type workspaceIndex map[string]map[string]*model.Environment
+
+func newWorkspaceIndex(workspaces []*model.Workspace) workspaceIndex {
+	index := make(workspaceIndex)
+	for _, ws := range workspaces {
+		envs := make(map[string]*model.Environment)
+		for _, env := range ws.Environments {
+			envs[env.Name] = env
+		}
+		index[ws.Name] = envs
+	}
+	return index
+}
+
+// orphanReason tells why a labeled resource no longer belongs to any persisted workspace, empty when it still does
+func (index workspaceIndex) orphanReason(labels map[string]string, requireActive bool) string {
+
+	wsName := labels["perun-workspace"]
+	envs, ok := index[wsName]
+	if !ok {
+		return fmt.Sprintf("workspace %s not found", wsName)
+	}
+	if envs == nil {
+		// the workspace yaml exists but couldn't be read, nothing can be told about its resources
+		return ""
+	}
+
+	envName, ok := labels["perun-env"]
+	if !ok {
+		return ""
+	}
+	env, ok := envs[envName]
+	if !ok {
+		return fmt.Sprintf("environment %s not found in workspace %s", envName, wsName)
+	}
+
+	if serviceName := labels["perun-service"]; serviceName != "" {
+		if _, ok := env.Services[serviceName]; !ok {
+			return fmt.Sprintf("service %s not found in environment %s/%s", serviceName, wsName, envName)
+		}
+	}
+
+	if requireActive && !isStartedStatus(env.Status) {
+		return fmt.Sprintf("environment %s/%s is not active", wsName, envName)
+	}
+
+	return ""
+}
+
+func (options PruneOptions) matches(workspace string, created time.Time) bool {
+	if options.Workspace != "" && options.Workspace != workspace {
+		return false
+	}
+	if options.OlderThan > 0 && !created.IsZero() && time.Since(created) < options.OlderThan {
+		return false
+	}
+	return true
+}
+
+// Prune finds the perun resources that don't match the persisted workspaces and removes them unless in dry run mode
+func Prune(workspaces []*model.Workspace, options PruneOptions) ([]PruneResource, error) {
+
+	ctx := context.Background()
+	clients, err := dockerClients.ForWorkspaces(workspaces)
+	if err != nil {
+		return nil, err
+	}
+
+	dirname, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch home directory : %v", err)
+	}
+	workspacesDirectory := dirname + utils.WORKSPACES_HOME
+
+	index := newWorkspaceIndex(workspaces)
+	entries, _ := os.ReadDir(workspacesDirectory)
+	for _, entry := range entries {
+		if _, ok := index[entry.Name()]; !ok && hasWorkspaceFile(filepath.Join(workspacesDirectory, entry.Name())) {
+			utils.Logger.Warn("workspace %s can't be read, skipping its resources", entry.Name())
+			index[entry.Name()] = nil
+		}
+	}
+
+	// each docker daemon the workspaces run on is pruned on its own
+	hosts := make([]string, 0, len(clients))
+	for host := range clients {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	orphans := make([]PruneResource, 0)
+	failures := make([]string, 0)
+	for _, host := range hosts {
+		cli := clients[host]
+		found, err := findDockerOrphans(ctx, cli, index, options)
+		if err != nil {
+			utils.Logger.Warn("skipping docker host %s : %v", host, err)
+			continue
+		}
+		orphans = append(orphans, found...)
+		if !options.DryRun {
+			if err := removeOrphans(ctx, cli, found); err != nil {
+				failures = append(failures, fmt.Sprintf("docker host %s : %v", host, err))
+			}
+		}
+	}
+
+	directories, err := findStaleDirectories(workspacesDirectory, workspaces, options)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, directories...)
+	if !options.DryRun {
+		if err := removeOrphans(ctx, nil, directories); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return orphans, errors.New(strings.Join(failures, "; "))
+	}
+	return orphans, nil
+}
+
+func findDockerOrphans(ctx context.Context, cli ContainerRuntime, index workspaceIndex, options PruneOptions) ([]PruneResource, error) {
+
+	orphans := make([]PruneResource, 0)
+	perunFilter := filters.NewArgs(filters.Arg("label", "provider=perun"))
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: perunFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list perun containers : %v", err)
+	}
+	for _, c := range containers {
+		mode := c.Labels["provider-mode"]
+		reason := index.orphanReason(c.Labels, mode == "sync")
+		if reason == "" && mode == "init" {
+			reason = "leftover pre-run container"
+		}
+		created := time.Unix(c.Created, 0)
+		if reason != "" && options.matches(c.Labels["perun-workspace"], created) {
+			orphans = append(orphans, PruneResource{Kind: "container", ID: c.ID, Name: containerName(c), Workspace: c.Labels["perun-workspace"], Env: c.Labels["perun-env"], Created: created, Reason: reason})
+		}
+	}
+
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{Filters: perunFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list perun networks : %v", err)
+	}
+	for _, n := range networks {
+		reason := index.orphanReason(n.Labels, false)
+		if reason != "" && options.matches(n.Labels["perun-workspace"], n.Created) {
+			orphans = append(orphans, PruneResource{Kind: "network", ID: n.ID, Name: n.Name, Workspace: n.Labels["perun-workspace"], Env: n.Labels["perun-env"], Created: n.Created, Reason: reason})
+		}
+	}
+
+	volumes, err := cli.VolumeList(ctx, perunFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list perun volumes : %v", err)
+	}
+	for _, v := range volumes.Volumes {
+		reason := index.orphanReason(v.Labels, false)
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		if reason != "" && options.matches(v.Labels["perun-workspace"], created) {
+			orphans = append(orphans, PruneResource{Kind: "volume", ID: v.Name, Name: v.Name, Workspace: v.Labels["perun-workspace"], Env: v.Labels["perun-env"], Created: created, Reason: reason})
+		}
+	}
+
+	images, err := cli.ImageList(ctx, types.ImageListOptions{Filters: perunFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list perun images : %v", err)
+	}
+	for _, image := range images {
+		reason := index.orphanReason(image.Labels, false)
+		created := time.Unix(image.Created, 0)
+		if reason != "" && options.matches(image.Labels["perun-workspace"], created) {
+			name := image.ID
+			if len(image.RepoTags) > 0 {
+				name = image.RepoTags[0]
+			}
+			orphans = append(orphans, PruneResource{Kind: "image", ID: image.ID, Name: name, Workspace: image.Labels["perun-workspace"], Env: image.Labels["perun-env"], Created: created, Reason: reason})
+		}
+	}
+
+	return orphans, nil
+}
+
+// findStaleDirectories lists workspace folders without a workspace.yml and service or environment folders the workspace no longer declares,
+// only the folders laid out by perun are listed
+func findStaleDirectories(workspacesDirectory string, workspaces []*model.Workspace, options PruneOptions) ([]PruneResource, error) {
+
+	stale := make([]PruneResource, 0)
+	entries, err := os.ReadDir(workspacesDirectory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stale, nil
+		}
+		return nil, fmt.Errorf("failed to read workspaces directory %s : %v", workspacesDirectory, err)
+	}
+
+	persisted := make(map[string]*model.Workspace)
+	for _, ws := range workspaces {
+		persisted[ws.Name] = ws
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		location := filepath.Join(workspacesDirectory, entry.Name())
+
+		ws, ok := persisted[entry.Name()]
+		if !ok {
+			if hasWorkspaceFile(location) {
+				continue
+			}
+			if !isPerunWorkspaceFolder(location) {
+				utils.Logger.Warn("keeping folder %s, it holds files perun did not create", location)
+				continue
+			}
+			if resource, ok := getStaleDirectory(location, entry.Name(), "no workspace.yml found", options); ok {
+				stale = append(stale, resource)
+			}
+			continue
+		}
+
+		// service folders sit next to the environment folders holding the persisted logs
+		services := make(map[string]bool)
+		for _, env := range ws.Environments {
+			services[env.Name] = true
+			for serviceName := range env.Services {
+				services[serviceName] = true
+			}
+		}
+
+		serviceEntries, err := os.ReadDir(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspace directory %s : %v", location, err)
+		}
+		for _, serviceEntry := range serviceEntries {
+			if !serviceEntry.IsDir() || services[serviceEntry.Name()] {
+				continue
+			}
+			serviceLocation := filepath.Join(location, serviceEntry.Name())
+			if !isPerunFolder(serviceLocation) {
+				utils.Logger.Warn("keeping folder %s, it holds files perun did not create", serviceLocation)
+				continue
+			}
+			reason := fmt.Sprintf("service %s not found in workspace %s", serviceEntry.Name(), ws.Name)
+			if resource, ok := getStaleDirectory(serviceLocation, ws.Name, reason, options); ok {
+				stale = append(stale, resource)
+			}
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Name < stale[j].Name
+	})
+	return stale, nil
+}
+
+// perunFolders are the folders perun creates under a service or environment folder, sources, properties, dumps and logs
+var perunFolders = map[string]bool{"src": true, "properties": true, "dump": true, "logs": true}
+
+// isPerunFolder tells whether a service or environment folder only holds what perun creates in it,
+// anything else may belong to the user and is never removed
+func isPerunFolder(location string) bool {
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && perunFolders[entry.Name()] {
+			continue
+		}
+		if !entry.IsDir() && entry.Name() == gitFetchStateFile {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// isPerunWorkspaceFolder tells whether a workspace folder only holds perun service and environment folders
+func isPerunWorkspaceFolder(location string) bool {
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !isPerunFolder(filepath.Join(location, entry.Name())) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasWorkspaceFile(location string) bool {
+	_, err := os.Stat(filepath.Join(location, "workspace.yml"))
+	return err == nil
+}
+
+func getStaleDirectory(location string, workspace string, reason string, options PruneOptions) (PruneResource, bool) {
+	info, err := os.Stat(location)
+	if err != nil || !options.matches(workspace, info.ModTime()) {
+		return PruneResource{}, false
+	}
+	return PruneResource{Kind: "directory", ID: location, Name: location, Workspace: workspace, Created: info.ModTime(), Reason: reason}, true
+}
+
+// removeOrphans removes containers first so that their networks, volumes and images are released
+func removeOrphans(ctx context.Context, cli ContainerRuntime, orphans []PruneResource) error {
+
+	failures := 0
+	for _, kind := range []string{"container", "network", "volume", "image", "directory"} {
+		for _, orphan := range orphans {
+			if orphan.Kind != kind {
+				continue
+			}
+
+			var err error
+			switch kind {
+			case "container":
+				err = cli.ContainerRemove(ctx, orphan.ID, types.ContainerRemoveOptions{Force: true})
+			case "network":
+				err = cli.NetworkRemove(ctx, orphan.ID)
+			case "volume":
+				err = cli.VolumeRemove(ctx, orphan.ID, false)
+			case "image":
+				_, err = cli.ImageRemove(ctx, orphan.ID, types.ImageRemoveOptions{PruneChildren: true})
+			case "directory":
+				err = os.RemoveAll(orphan.ID)
+			}
+
+			if err != nil && !client.IsErrNotFound(err) {
+				utils.Logger.Warn("failed to remove %s %s : %v", kind, orphan.Name, err)
+				failures++
+				continue
+			}
+			utils.Logger.Info("removed %s %s", kind, orphan.Name)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("failed to remove %d of %d orphaned resources", failures, len(orphans))
+	}
+	return nil
+}
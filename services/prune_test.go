@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphanReason(t *testing.T) {
+
+	index := newWorkspaceIndex([]*model.Workspace{{
+		Name: "testWS",
+		Environments: []*model.Environment{{
+			Name:     "testEnv",
+			Status:   model.INACTIVE_STATUS,
+			Services: map[string]*model.Service{"api": {Name: "api"}},
+		}},
+	}})
+	index["brokenWS"] = nil
+
+	assert.Equal(t, "workspace otherWS not found", index.orphanReason(map[string]string{"perun-workspace": "otherWS"}, false))
+	assert.Equal(t, "", index.orphanReason(map[string]string{"perun-workspace": "brokenWS", "perun-env": "any"}, true))
+	assert.Equal(t, "", index.orphanReason(map[string]string{"perun-workspace": "testWS"}, false))
+	assert.Equal(t, "environment gone not found in workspace testWS", index.orphanReason(map[string]string{"perun-workspace": "testWS", "perun-env": "gone"}, false))
+	assert.Equal(t, "service db not found in environment testWS/testEnv", index.orphanReason(map[string]string{"perun-workspace": "testWS", "perun-env": "testEnv", "perun-service": "db"}, false))
+	assert.Equal(t, "", index.orphanReason(map[string]string{"perun-workspace": "testWS", "perun-env": "testEnv", "perun-service": "api"}, false))
+	assert.Equal(t, "environment testWS/testEnv is not active", index.orphanReason(map[string]string{"perun-workspace": "testWS", "perun-env": "testEnv", "perun-service": "api"}, true))
+}
+
+func TestFindStaleDirectories(t *testing.T) {
+	setTestLogger(t)
+
+	root := t.TempDir()
+	for _, dir := range []string{"testWS/api/properties", "testWS/testEnv/logs/api", "testWS/removed/dump", "testWS/notes", "testWS/custom/src", "deletedWS/api/src", "brokenWS", "userWS/data/backup"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(root, dir), os.ModePerm))
+	}
+	// folders holding anything perun did not create are kept
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "testWS", "removed", gitFetchStateFile), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "testWS", "notes", "todo.txt"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "testWS", "custom", "run.sh"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "testWS", "workspace.yml"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "brokenWS", "workspace.yml"), []byte("{"), 0644))
+
+	workspaces := []*model.Workspace{{
+		Name:         "testWS",
+		Environments: []*model.Environment{{Name: "testEnv", Services: map[string]*model.Service{"api": {Name: "api"}}}},
+	}}
+
+	stale, err := findStaleDirectories(root, workspaces, PruneOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, stale, 2)
+	assert.Equal(t, filepath.Join(root, "deletedWS"), stale[0].Name)
+	assert.Equal(t, "no workspace.yml found", stale[0].Reason)
+	assert.Equal(t, filepath.Join(root, "testWS", "removed"), stale[1].Name)
+
+	stale, err = findStaleDirectories(root, workspaces, PruneOptions{Workspace: "testWS"})
+	assert.Nil(t, err)
+	assert.Len(t, stale, 1)
+
+	stale, err = findStaleDirectories(root, workspaces, PruneOptions{OlderThan: time.Hour})
+	assert.Nil(t, err)
+	assert.Len(t, stale, 0)
+}
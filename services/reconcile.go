@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+const SPEC_HASH_LABEL = "perun-spec-hash"
+
+// ListEnvironmentContainers returns the synchronized containers of an environment, found by their perun labels and keyed by service name
+func ListEnvironmentContainers(ctx context.Context, cli ContainerRuntime, env *model.Environment) (map[string][]types.Container, error) {
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", "provider=perun"),
+			filters.Arg("label", "provider-mode=sync"),
+			filters.Arg("label", "perun-workspace="+env.Workspace),
+			filters.Arg("label", "perun-env="+env.Name),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list env %s/%s containers : %v", env.Workspace, env.Name, err)
+	}
+
+	byService := make(map[string][]types.Container)
+	for _, c := range containers {
+		serviceName := c.Labels["perun-service"]
+		byService[serviceName] = append(byService[serviceName], c)
+	}
+
+	return byService, nil
+}
+
+// GetContainerName returns the name of a service container, unique across workspaces
+func GetContainerName(env *model.Environment, serviceName string) string {
+	return env.Workspace + "-" + env.Name + "-" + serviceName
+}
+
+// FindServiceContainer returns the synchronized container of a service found by its perun labels, nil when there is none
+func FindServiceContainer(ctx context.Context, cli ContainerRuntime, workspace string, envName string, serviceName string) (*types.Container, error) {
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", "provider=perun"),
+			filters.Arg("label", "provider-mode=sync"),
+			filters.Arg("label", "perun-workspace="+workspace),
+			filters.Arg("label", "perun-env="+envName),
+			filters.Arg("label", "perun-service="+serviceName),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service %s/%s/%s containers : %v", workspace, envName, serviceName, err)
+	}
+
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	if len(containers) > 1 {
+		return nil, fmt.Errorf("too many containers for service %s/%s/%s", workspace, envName, serviceName)
+	}
+	return &containers[0], nil
+}
+
+// getSpecHash fingerprints the desired container spec, a running container is only recreated when its fingerprint changes
+func getSpecHash(config *container.Config, hostConfig *container.HostConfig, platform string, imageID string, service *model.Service) (string, error) {
+
+	mounts := append([]mount.Mount{}, hostConfig.Mounts...)
+	sort.Slice(mounts, func(i, j int) bool {
+		return mounts[i].Target < mounts[j].Target
+	})
+	sortedHostConfig := *hostConfig
+	sortedHostConfig.Mounts = mounts
+
+	data, err := json.Marshal(struct {
+		Config     *container.Config
+		HostConfig *container.HostConfig
+		Platform   string
+		ImageID    string
+		Run        *model.RunConfig
+		PreRun     []model.Command
+		PostRun    []model.Command
+	}{config, &sortedHostConfig, platform, imageID, service.Run, service.PreRun, service.PostRun})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func removeContainer(ctx context.Context, cli ContainerRuntime, c types.Container) error {
+
+	utils.Logger.Info("removing container %s with ID %s", containerName(c), c.ID)
+	if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		RemoveLinks:   false,
+		Force:         true,
+	}); err != nil {
+		return fmt.Errorf("failed to remove container %s : %v", containerName(c), err)
+	}
+
+	return nil
+}
+
+func containerName(c types.Container) string {
+	if len(c.Names) > 0 {
+		return c.Names[0][1:]
+	}
+	return c.ID
+}
+
+// removeOrphanContainers removes the synchronized containers of services that are no longer part of the environment
+func removeOrphanContainers(ctx context.Context, cli ContainerRuntime, env *model.Environment, existing map[string][]types.Container) error {
+
+	for serviceName, containers := range existing {
+		if _, ok := env.Services[serviceName]; ok {
+			continue
+		}
+		utils.Logger.Info("service %s was removed from env %s/%s, removing its containers", serviceName, env.Workspace, env.Name)
+		for _, c := range containers {
+			if err := removeContainer(ctx, cli, c); err != nil {
+				return err
+			}
+		}
+		delete(existing, serviceName)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpecHash(t *testing.T) {
+
+	service := &model.Service{Name: "testService", Run: &model.RunConfig{Cmd: model.ExecCommand{"run"}}}
+	config := &container.Config{Image: "redis:7.0", Env: []string{"A=1"}}
+	hostConfig := &container.HostConfig{Mounts: []mount.Mount{
+		{Type: mount.TypeBind, Source: "/src", Target: "/app"},
+		{Type: mount.TypeBind, Source: "/conf", Target: "/etc/conf"},
+	}}
+
+	hash, err := getSpecHash(config, hostConfig, "linux/amd64", "sha256:1", service)
+	assert.Nil(t, err)
+
+	// mount order does not matter
+	reordered := &container.HostConfig{Mounts: []mount.Mount{hostConfig.Mounts[1], hostConfig.Mounts[0]}}
+	same, err := getSpecHash(config, reordered, "linux/amd64", "sha256:1", service)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, same)
+
+	changedEnv := &container.Config{Image: "redis:7.0", Env: []string{"A=2"}}
+	changed, err := getSpecHash(changedEnv, hostConfig, "linux/amd64", "sha256:1", service)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, changed)
+
+	changed, err = getSpecHash(config, hostConfig, "linux/amd64", "sha256:2", service)
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, changed)
+}
+
+func TestGetContainerName(t *testing.T) {
+
+	env := &model.Environment{Name: "boutique", Workspace: "testWS"}
+	assert.Equal(t, "testWS-boutique-cart", GetContainerName(env, "cart"))
+}
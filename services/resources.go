@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	units "github.com/docker/go-units"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"main.go/model"
+)
+
+// docker refuses memory limits below 6MiB
+const MIN_MEMORY = 6 * 1024 * 1024
+
+// GetContainerResources maps the service resources onto the docker host resources, docker has no hard cpu reservation
+// so a cpu reservation is translated into a relative cpu shares weight, 1024 per core
+func GetContainerResources(service *model.Service) (container.Resources, error) {
+
+	resources := container.Resources{}
+	if service.Run == nil || service.Run.Resources == nil {
+		return resources, nil
+	}
+	spec := service.Run.Resources
+
+	if spec.Limits != nil {
+		if spec.Limits.CPUs != "" {
+			milliCPUs, err := parseCPUs(spec.Limits.CPUs)
+			if err != nil {
+				return resources, fmt.Errorf("invalid service %s cpu limit : %v", service.Name, err)
+			}
+			resources.NanoCPUs = milliCPUs * 1e6
+		}
+		if spec.Limits.Memory != "" {
+			memory, err := parseMemory(spec.Limits.Memory)
+			if err != nil {
+				return resources, fmt.Errorf("invalid service %s memory limit : %v", service.Name, err)
+			}
+			resources.Memory = memory
+		}
+	}
+
+	if spec.Reservations != nil {
+		if spec.Reservations.CPUs != "" {
+			milliCPUs, err := parseCPUs(spec.Reservations.CPUs)
+			if err != nil {
+				return resources, fmt.Errorf("invalid service %s cpu reservation : %v", service.Name, err)
+			}
+			resources.CPUShares = milliCPUs * 1024 / 1000
+			if resources.CPUShares < 2 {
+				resources.CPUShares = 2
+			}
+		}
+		if spec.Reservations.Memory != "" {
+			memory, err := parseMemory(spec.Reservations.Memory)
+			if err != nil {
+				return resources, fmt.Errorf("invalid service %s memory reservation : %v", service.Name, err)
+			}
+			resources.MemoryReservation = memory
+		}
+	}
+
+	if resources.Memory > 0 && resources.MemoryReservation > resources.Memory {
+		return resources, fmt.Errorf("service %s memory reservation %s exceeds its memory limit %s", service.Name, spec.Reservations.Memory, spec.Limits.Memory)
+	}
+
+	return resources, nil
+}
+
+// parseCPUs returns the cpus value in millicores
+func parseCPUs(value string) (int64, error) {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil || quantity.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid cpus value %s", value)
+	}
+	return quantity.MilliValue(), nil
+}
+
+func parseMemory(value string) (int64, error) {
+	memory, err := parseBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory value %s", value)
+	}
+	if memory < MIN_MEMORY {
+		return 0, fmt.Errorf("memory value %s is below the 6Mi minimum", value)
+	}
+	return memory, nil
+}
+
+// parseBytes returns a size in bytes, docker notation is tried first so 512m stays 512 megabytes
+func parseBytes(value string) (int64, error) {
+	size, err := units.RAMInBytes(value)
+	if err == nil {
+		return size, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+	return quantity.Value(), nil
+}
+
+// getK8sResources converts the k8s container limits and requests into service resources, multiplied by the given scale factor
+func getK8sResources(requirements corev1.ResourceRequirements, scale float64) *model.Resources {
+
+	limits := scaleResourceList(requirements.Limits, scale)
+	reservations := scaleResourceList(requirements.Requests, scale)
+	if limits == nil && reservations == nil {
+		return nil
+	}
+
+	return &model.Resources{
+		Limits:       limits,
+		Reservations: reservations,
+	}
+}
+
+func scaleResourceList(list corev1.ResourceList, scale float64) *model.ResourceList {
+
+	result := &model.ResourceList{}
+	if cpu, ok := list[corev1.ResourceCPU]; ok {
+		milliCPUs := int64(float64(cpu.MilliValue()) * scale)
+		if milliCPUs < 1 {
+			milliCPUs = 1
+		}
+		result.CPUs = resource.NewMilliQuantity(milliCPUs, resource.DecimalSI).String()
+	}
+	if memory, ok := list[corev1.ResourceMemory]; ok {
+		bytes := int64(float64(memory.Value()) * scale)
+		if bytes < MIN_MEMORY {
+			bytes = MIN_MEMORY
+		}
+		result.Memory = resource.NewQuantity(bytes, resource.BinarySI).String()
+	}
+
+	if result.CPUs == "" && result.Memory == "" {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContainerResources(t *testing.T) {
+
+	service := &model.Service{
+		Name: "testService",
+		Run: &model.RunConfig{
+			Resources: &model.Resources{
+				Limits:       &model.ResourceList{CPUs: "1.5", Memory: "1g"},
+				Reservations: &model.ResourceList{CPUs: "500m", Memory: "512Mi"},
+			},
+		},
+	}
+
+	resources, err := GetContainerResources(service)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500000000), resources.NanoCPUs)
+	assert.Equal(t, int64(1024*1024*1024), resources.Memory)
+	assert.Equal(t, int64(512), resources.CPUShares)
+	assert.Equal(t, int64(512*1024*1024), resources.MemoryReservation)
+
+	service.Run.Resources.Reservations.Memory = "2Gi"
+	_, err = GetContainerResources(service)
+	assert.EqualError(t, err, "service testService memory reservation 2Gi exceeds its memory limit 1g")
+
+	service.Run.Resources.Limits.CPUs = "lots"
+	_, err = GetContainerResources(service)
+	assert.EqualError(t, err, "invalid service testService cpu limit : invalid cpus value lots")
+}
+
+func TestGetK8sResources(t *testing.T) {
+
+	requirements := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("500m"),
+		},
+	}
+
+	resources := getK8sResources(requirements, 0.25)
+	assert.Equal(t, &model.ResourceList{CPUs: "500m", Memory: "1Gi"}, resources.Limits)
+	assert.Equal(t, &model.ResourceList{CPUs: "125m"}, resources.Reservations)
+
+	assert.Nil(t, getK8sResources(corev1.ResourceRequirements{}, 1))
+}
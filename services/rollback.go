@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"main.go/utils"
+)
+
+const (
+	CONTAINER_RESOURCE = "container"
+	NETWORK_RESOURCE   = "network"
+	VOLUME_RESOURCE    = "volume"
+)
+
+type createdResource struct {
+	Kind string
+	ID   string
+	Name string
+}
+
+// activationJournal records the docker resources created while activating an environment,
+// so a failed activation can be undone, a nil journal records nothing
+type activationJournal struct {
+	lock      sync.Mutex
+	resources []createdResource
+}
+
+func (j *activationJournal) record(kind string, id string, name string) {
+	if j == nil {
+		return
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.resources = append(j.resources, createdResource{Kind: kind, ID: id, Name: name})
+}
+
+// rollback removes the recorded resources in reverse creation order, it keeps going on failures and reports them all at the end
+func (j *activationJournal) rollback(cli ContainerRuntime) error {
+	if j == nil {
+		return nil
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	// the activation context may be cancelled already, the teardown must still go through
+	ctx := context.Background()
+	failed := 0
+	for i := len(j.resources) - 1; i >= 0; i-- {
+		resource := j.resources[i]
+		utils.Logger.Info("rolling back %s %s", resource.Kind, resource.Name)
+
+		var err error
+		switch resource.Kind {
+		case CONTAINER_RESOURCE:
+			err = cli.ContainerRemove(ctx, resource.ID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+		case NETWORK_RESOURCE:
+			err = cli.NetworkRemove(ctx, resource.ID)
+		case VOLUME_RESOURCE:
+			err = cli.VolumeRemove(ctx, resource.ID, true)
+		}
+		if err != nil && !client.IsErrNotFound(err) {
+			utils.Logger.Error("failed to roll back %s %s : %v", resource.Kind, resource.Name, err)
+			failed++
+		}
+	}
+	j.resources = nil
+
+	if failed > 0 {
+		return fmt.Errorf("failed to roll back %d resources, remove them manually or run perun prune", failed)
+	}
+	return nil
+}
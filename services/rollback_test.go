@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+	"main.go/model"
+)
+
+func TestActivationJournal(t *testing.T) {
+
+	var noJournal *activationJournal
+	noJournal.record(CONTAINER_RESOURCE, "id", "name")
+	assert.Nil(t, noJournal.rollback(nil))
+
+	journal := &activationJournal{}
+	journal.record(NETWORK_RESOURCE, "net-id", "ws-env")
+	journal.record(VOLUME_RESOURCE, "perun-ws-env-data", "perun-ws-env-data")
+	journal.record(CONTAINER_RESOURCE, "container-id", "ws-env-api")
+
+	assert.Equal(t, []createdResource{
+		{Kind: NETWORK_RESOURCE, ID: "net-id", Name: "ws-env"},
+		{Kind: VOLUME_RESOURCE, ID: "perun-ws-env-data", Name: "perun-ws-env-data"},
+		{Kind: CONTAINER_RESOURCE, ID: "container-id", Name: "ws-env-api"},
+	}, journal.resources)
+}
+
+func TestActivationJournalRollback(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	ctx := context.Background()
+
+	journal := &activationJournal{}
+	network, err := runtime.NetworkCreate(ctx, "ws-env", types.NetworkCreate{})
+	assert.Nil(t, err)
+	journal.record(NETWORK_RESOURCE, network.ID, "ws-env")
+	volume, err := runtime.VolumeCreate(ctx, volumetypes.VolumeCreateBody{Name: "perun-ws-env-data"})
+	assert.Nil(t, err)
+	journal.record(VOLUME_RESOURCE, volume.Name, volume.Name)
+	created, err := runtime.ContainerCreate(ctx, &container.Config{Image: "nginx"}, &container.HostConfig{}, nil, nil, "ws-env-api")
+	assert.Nil(t, err)
+	journal.record(CONTAINER_RESOURCE, created.ID, "ws-env-api")
+	// a resource removed in the meantime is skipped
+	journal.record(CONTAINER_RESOURCE, "gone-id", "ws-env-gone")
+	steps := len(runtime.Plan())
+
+	assert.Nil(t, journal.rollback(runtime))
+	assert.Equal(t, []string{
+		"remove container ws-env-api",
+		"remove volume perun-ws-env-data",
+		"remove network ws-env",
+	}, runtime.Plan()[steps:])
+	assert.Len(t, journal.resources, 0)
+}
+
+func TestActivateRollbackKeepsPreviousContainers(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	api := env.Services["api"]
+	delete(env.Services, "api")
+	assert.Nil(t, s.Synchronize(env))
+
+	// the cache container is up to date and kept, the env reports what is still running
+	env.Services["api"] = api
+	api.PullPolicy = model.PULL_NEVER
+	assert.NotNil(t, s.Activate(env, false))
+	assert.Equal(t, model.ACTIVE_STATUS, env.Status)
+	assert.Equal(t, model.ACTIVE_STATUS, env.Services["cache"].Status)
+	assert.Equal(t, model.INACTIVE_STATUS, env.Services["api"].Status)
+
+	// resources kept for debugging leave the env in a state a deactivation accepts
+	env = getTestSyncEnvironment()
+	env.Name = "otherEnv"
+	env.Services["cache"].Params["image"] = "redis:8"
+	env.Services["cache"].PullPolicy = model.PULL_NEVER
+	assert.NotNil(t, s.Activate(env, true))
+	assert.Equal(t, model.STOPPED_STATUS, env.Status)
+	network, err := findNetwork(context.Background(), runtime, "testWS-otherEnv")
+	assert.Nil(t, err)
+	assert.NotNil(t, network)
+}
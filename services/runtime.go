@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	corev1 "k8s.io/api/core/v1"
+
+	"main.go/model"
+)
+
+// DEFAULT_RESTART_POLICY never restarts a service, a crashing service fails the readiness wait instead of hiding behind restarts
+const DEFAULT_RESTART_POLICY = "no"
+
+// GetRestartPolicy parses a docker restart policy, one of no, always, unless-stopped or on-failure[:max-retries]
+func GetRestartPolicy(policy string) (container.RestartPolicy, error) {
+
+	if policy == "" {
+		policy = DEFAULT_RESTART_POLICY
+	}
+
+	name, retries, hasRetries := strings.Cut(policy, ":")
+	switch name {
+	case "no", "always", "unless-stopped":
+		if hasRetries {
+			return container.RestartPolicy{}, fmt.Errorf("restart policy %s doesn't support a maximum retry count", name)
+		}
+		return container.RestartPolicy{Name: name}, nil
+	case "on-failure":
+		restartPolicy := container.RestartPolicy{Name: name}
+		if hasRetries {
+			count, err := strconv.Atoi(retries)
+			if err != nil || count < 0 {
+				return container.RestartPolicy{}, fmt.Errorf("invalid restart policy %s, bad maximum retry count %s", policy, retries)
+			}
+			restartPolicy.MaximumRetryCount = count
+		}
+		return restartPolicy, nil
+	default:
+		return container.RestartPolicy{}, fmt.Errorf("unsupported restart policy %s, expected one of no, always, unless-stopped or on-failure[:max-retries]", policy)
+	}
+}
+
+// applyRuntimeOptions sets the service entrypoint, working directory, user, hostname, hosts, dns, restart policy and stop timeout
+func applyRuntimeOptions(service *model.Service, config *container.Config, hostConfig *container.HostConfig) error {
+
+	runConfig := service.Run
+
+	if runConfig.Shell && len(runConfig.Cmd) > 0 && len(runConfig.Entrypoint) > 0 {
+		return fmt.Errorf("service %s runs a shell command and can't set an entrypoint too", service.Name)
+	}
+	if entrypoint := GetContainerEntrypoint(service); len(entrypoint) > 0 {
+		config.Entrypoint = entrypoint
+	}
+	config.WorkingDir = runConfig.WorkingDir
+	config.User = runConfig.User
+	config.Hostname = runConfig.Hostname
+
+	for _, host := range runConfig.ExtraHosts {
+		if name, ip, ok := strings.Cut(host, ":"); !ok || name == "" || ip == "" {
+			return fmt.Errorf("invalid extra host %s for service %s, expected hostname:ip", host, service.Name)
+		}
+	}
+	hostConfig.ExtraHosts = runConfig.ExtraHosts
+	hostConfig.DNS = runConfig.DNS
+	hostConfig.DNSSearch = runConfig.DNSSearch
+	hostConfig.DNSOptions = runConfig.DNSOptions
+
+	restartPolicy, err := GetRestartPolicy(runConfig.Restart)
+	if err != nil {
+		return fmt.Errorf("invalid service %s restart policy : %v", service.Name, err)
+	}
+	hostConfig.RestartPolicy = restartPolicy
+
+	if runConfig.StopTimeout != "" {
+		timeout, err := time.ParseDuration(runConfig.StopTimeout)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid service %s stop timeout %s", service.Name, runConfig.StopTimeout)
+		}
+		seconds := int(timeout.Seconds())
+		config.StopTimeout = &seconds
+	}
+
+	return nil
+}
+
+// getK8sUser returns the uid[:gid] the k8s container runs as, the container security context overrides the pod one
+func getK8sUser(podContext *corev1.PodSecurityContext, containerContext *corev1.SecurityContext) string {
+
+	var uid, gid *int64
+	if podContext != nil {
+		uid, gid = podContext.RunAsUser, podContext.RunAsGroup
+	}
+	if containerContext != nil {
+		if containerContext.RunAsUser != nil {
+			uid = containerContext.RunAsUser
+		}
+		if containerContext.RunAsGroup != nil {
+			gid = containerContext.RunAsGroup
+		}
+	}
+
+	if uid == nil {
+		return ""
+	}
+	if gid == nil {
+		return strconv.FormatInt(*uid, 10)
+	}
+	return strconv.FormatInt(*uid, 10) + ":" + strconv.FormatInt(*gid, 10)
+}
+
+func getK8sExtraHosts(hostAliases []corev1.HostAlias) []string {
+
+	extraHosts := make([]string, 0)
+	for _, alias := range hostAliases {
+		for _, hostname := range alias.Hostnames {
+			extraHosts = append(extraHosts, hostname+":"+alias.IP)
+		}
+	}
+
+	if len(extraHosts) == 0 {
+		return nil
+	}
+	return extraHosts
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	corev1 "k8s.io/api/core/v1"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRestartPolicy(t *testing.T) {
+
+	policy, err := GetRestartPolicy("")
+	assert.Nil(t, err)
+	assert.Equal(t, container.RestartPolicy{Name: "no"}, policy)
+
+	policy, err = GetRestartPolicy("on-failure:10")
+	assert.Nil(t, err)
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 10}, policy)
+
+	policy, err = GetRestartPolicy("unless-stopped")
+	assert.Nil(t, err)
+	assert.Equal(t, container.RestartPolicy{Name: "unless-stopped"}, policy)
+
+	_, err = GetRestartPolicy("always:3")
+	assert.EqualError(t, err, "restart policy always doesn't support a maximum retry count")
+
+	_, err = GetRestartPolicy("sometimes")
+	assert.NotNil(t, err)
+}
+
+func TestApplyRuntimeOptions(t *testing.T) {
+
+	service := &model.Service{
+		Name: "testService",
+		Run: &model.RunConfig{
+			Entrypoint:  []string{"/docker-entrypoint.sh"},
+			WorkingDir:  "/srv",
+			User:        "1000:1000",
+			ExtraHosts:  []string{"db.local:10.0.0.5"},
+			Restart:     "no",
+			StopTimeout: "30s",
+		},
+	}
+	config := &container.Config{}
+	hostConfig := &container.HostConfig{}
+
+	err := applyRuntimeOptions(service, config, hostConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/docker-entrypoint.sh"}, []string(config.Entrypoint))
+	assert.Equal(t, "/srv", config.WorkingDir)
+	assert.Equal(t, "1000:1000", config.User)
+	assert.Equal(t, 30, *config.StopTimeout)
+	assert.Equal(t, []string{"db.local:10.0.0.5"}, hostConfig.ExtraHosts)
+	assert.Equal(t, "no", hostConfig.RestartPolicy.Name)
+
+	service.Run.ExtraHosts = []string{"db.local"}
+	err = applyRuntimeOptions(service, config, hostConfig)
+	assert.EqualError(t, err, "invalid extra host db.local for service testService, expected hostname:ip")
+
+	service.Run.ExtraHosts = nil
+	service.Run.Cmd = model.ExecCommand{"./start.sh"}
+	service.Run.Shell = true
+	err = applyRuntimeOptions(service, config, hostConfig)
+	assert.EqualError(t, err, "service testService runs a shell command and can't set an entrypoint too")
+}
+
+func TestGetK8sUser(t *testing.T) {
+
+	podUser, containerUser, group := int64(1000), int64(2000), int64(3000)
+
+	assert.Equal(t, "", getK8sUser(nil, nil))
+	assert.Equal(t, "1000:3000", getK8sUser(&corev1.PodSecurityContext{RunAsUser: &podUser, RunAsGroup: &group}, nil))
+	assert.Equal(t, "2000", getK8sUser(&corev1.PodSecurityContext{RunAsUser: &podUser}, &corev1.SecurityContext{RunAsUser: &containerUser}))
+}
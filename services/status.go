@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	"main.go/model"
+)
+
+const MISSING_STATE = "missing"
+
+// ServiceStatus is the state of a service container as observed in docker
+type ServiceStatus struct {
+	Service      string
+	Container    string
+	State        string
+	Health       string
+	StartedAt    time.Time
+	RestartCount int
+	ImageDigest  string
+	Ports        []string
+	Debug        bool
+	Status       string
+}
+
+// Observe inspects the containers of every environment service, the saved statuses are left untouched
+func (s DockerSynchronizationService) Observe(env *model.Environment) ([]*ServiceStatus, error) {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
+	if err != nil {
+		return nil, err
+	}
+	debugged, err := listDebuggedServices(ctx, cli, env)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(env.Services))
+	for name := range env.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]*ServiceStatus, 0, len(names))
+	for _, name := range names {
+		status := &ServiceStatus{
+			Service: name,
+			State:   MISSING_STATE,
+			Debug:   debugged[name],
+		}
+		statuses = append(statuses, status)
+
+		containers := existing[name]
+		if len(containers) == 0 {
+			status.Status = getObservedStatus(status.State, status.Debug)
+			continue
+		}
+
+		c := containers[0]
+		status.Container = containerName(c)
+		status.Ports = getPublishedPorts(c)
+
+		inspect, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect service %s container : %v", name, err)
+		}
+		status.State = inspect.State.Status
+		status.Health = "-"
+		if inspect.State.Health != nil {
+			status.Health = inspect.State.Health.Status
+		}
+		if inspect.State.Running {
+			status.StartedAt, _ = time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+		}
+		status.RestartCount = inspect.RestartCount
+		status.ImageDigest = getImageDigest(ctx, cli, inspect.Image)
+		status.Status = getObservedStatus(status.State, status.Debug)
+	}
+
+	return statuses, nil
+}
+
+// listDebuggedServices returns the services whose container is currently replaced by a running debug container
+func listDebuggedServices(ctx context.Context, cli ContainerRuntime, env *model.Environment) (map[string]bool, error) {
+
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", "provider=perun"),
+			filters.Arg("label", "provider-mode=debug"),
+			filters.Arg("label", "perun-workspace="+env.Workspace),
+			filters.Arg("label", "perun-env="+env.Name),
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list env %s/%s debug containers : %v", env.Workspace, env.Name, err)
+	}
+
+	debugged := make(map[string]bool)
+	for _, c := range containers {
+		debugged[c.Labels["perun-service"]] = true
+	}
+	return debugged, nil
+}
+
+// getImageDigest returns the registry digest of the image, or its local ID when it was never pushed or pulled by digest
+func getImageDigest(ctx context.Context, cli ContainerRuntime, imageID string) string {
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, imageID)
+	if err != nil || len(inspect.RepoDigests) == 0 {
+		return imageID
+	}
+	return inspect.RepoDigests[0]
+}
+
+func getPublishedPorts(c types.Container) []string {
+	ports := make([]string, 0, len(c.Ports))
+	seen := make(map[string]bool)
+	for _, port := range c.Ports {
+		if port.PublicPort == 0 {
+			continue
+		}
+		// docker reports a binding per ip family, they are shown once
+		mapping := fmt.Sprintf("%d->%d/%s", port.PublicPort, port.PrivatePort, port.Type)
+		if seen[mapping] {
+			continue
+		}
+		seen[mapping] = true
+		ports = append(ports, mapping)
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+// getObservedStatus maps a docker container state to a perun service status
+func getObservedStatus(state string, debug bool) string {
+	if debug {
+		return model.ACTIVE_STATUS
+	}
+	switch state {
+	case "running", "restarting":
+		return model.ACTIVE_STATUS
+	case "paused":
+		return model.PAUSED_STATUS
+	case MISSING_STATE:
+		return model.INACTIVE_STATUS
+	default:
+		return model.STOPPED_STATUS
+	}
+}
+
+// getObservedEnvironmentStatus derives the environment status out of its services observed statuses,
+// the environment is active as soon as one of its services is
+func getObservedEnvironmentStatus(statuses []*ServiceStatus) string {
+	counts := make(map[string]int)
+	for _, status := range statuses {
+		counts[status.Status]++
+	}
+
+	switch {
+	case counts[model.ACTIVE_STATUS] > 0:
+		return model.ACTIVE_STATUS
+	case counts[model.PAUSED_STATUS] > 0:
+		return model.PAUSED_STATUS
+	case counts[model.STOPPED_STATUS] > 0:
+		return model.STOPPED_STATUS
+	default:
+		return model.INACTIVE_STATUS
+	}
+}
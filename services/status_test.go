@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+
+	"main.go/model"
+)
+
+func TestGetObservedStatus(t *testing.T) {
+	assert.Equal(t, model.ACTIVE_STATUS, getObservedStatus("running", false))
+	assert.Equal(t, model.ACTIVE_STATUS, getObservedStatus("restarting", false))
+	assert.Equal(t, model.PAUSED_STATUS, getObservedStatus("paused", false))
+	assert.Equal(t, model.STOPPED_STATUS, getObservedStatus("exited", false))
+	assert.Equal(t, model.INACTIVE_STATUS, getObservedStatus(MISSING_STATE, false))
+	// a debug container stands in for the stopped original
+	assert.Equal(t, model.ACTIVE_STATUS, getObservedStatus("exited", true))
+}
+
+func TestGetObservedEnvironmentStatus(t *testing.T) {
+	assert.Equal(t, model.INACTIVE_STATUS, getObservedEnvironmentStatus(nil))
+	assert.Equal(t, model.ACTIVE_STATUS, getObservedEnvironmentStatus([]*ServiceStatus{{Status: model.STOPPED_STATUS}, {Status: model.ACTIVE_STATUS}}))
+	assert.Equal(t, model.PAUSED_STATUS, getObservedEnvironmentStatus([]*ServiceStatus{{Status: model.PAUSED_STATUS}, {Status: model.INACTIVE_STATUS}}))
+}
+
+func TestGetPublishedPorts(t *testing.T) {
+	c := types.Container{Ports: []types.Port{
+		{IP: "0.0.0.0", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+		{IP: "::", PrivatePort: 80, PublicPort: 8080, Type: "tcp"},
+		{PrivatePort: 9090, Type: "tcp"},
+	}}
+	assert.Equal(t, []string{"8080->80/tcp"}, getPublishedPorts(c))
+}
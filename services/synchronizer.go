@@ -2,12 +2,14 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -18,7 +20,6 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
 	ps "github.com/mitchellh/go-ps"
 	"main.go/model"
@@ -27,13 +28,27 @@ import (
 
 type SynchronizationService interface {
 	Synchronize(*model.Environment) error
+	Activate(env *model.Environment, keepOnFailure bool) error
 	Unsynchronize(*model.Environment) error
 	Destroy(*model.Environment) error
+	PurgeVolumes(*model.Environment) error
+	Pause(env *model.Environment, service string, stop bool) error
+	Resume(env *model.Environment, service string) error
+	Restart(env *model.Environment, service string) error
+	Recreate(env *model.Environment, service string) error
+	Observe(env *model.Environment) ([]*ServiceStatus, error)
+	Plan(env *model.Environment) ([]string, error)
 }
 
 type DockerSynchronizationService struct {
+	// Runtime replaces the workspace docker daemon when set, e.g. by an in-memory runtime
+	Runtime ContainerRuntime
+	// Host replaces the local host steps when set, e.g. by recorded ones
+	Host HostRunner
 }
 
+const EVENTS_ENDPOINTS_REFRESH = 30 * time.Second
+
 //service type -
 //service source : git/local/docker
 //service build config: how to build the executable and the wrraping docker
@@ -41,76 +56,213 @@ type DockerSynchronizationService struct {
 
 func (s DockerSynchronizationService) Synchronize(env *model.Environment) error {
 
-	allocation := utils.Logger.GetProgressAllocation(env.Name)
-	ctx := context.Background()
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := s.getRuntime(env.Workspace)
 	if err != nil {
 		return err
 	}
 
-	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
-		Filters: filters.NewArgs(filters.KeyValuePair{Key: "name", Value: env.Workspace}),
-	})
+	return synchronize(context.Background(), cli, s.getHost(), env, nil)
+}
 
-	var targetNetworkID string
+// Activate synchronizes an inactive environment as a single transaction, the resources it created are torn down
+// in reverse order when a service fails or the activation is interrupted, unless keepOnFailure is set,
+// containers kept up to date from an earlier activation keep running and outdated containers replaced during the
+// activation are not restored, the next activation creates them again, the environment then takes its observed status
+func (s DockerSynchronizationService) Activate(env *model.Environment, keepOnFailure bool) error {
 
-	if len(networks) > 1 {
-		return fmt.Errorf("failed to synchronize env %s, Too many networks with the same workspace name %s", env.Name, env.Workspace)
-	} else if len(networks) == 1 {
-		targetNetworkID = networks[0].ID
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
 
-	} else if len(networks) == 0 {
-		resp, err := cli.NetworkCreate(ctx, env.Workspace, types.NetworkCreate{
-			CheckDuplicate: true,
-			Attachable:     true,
-		})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	journal := &activationJournal{}
+	err = synchronize(ctx, cli, s.getHost(), env, journal)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		err = fmt.Errorf("activation of env %s/%s was interrupted : %v", env.Workspace, env.Name, err)
+	}
+	utils.Logger.Error("%v", err)
+
+	if keepOnFailure {
+		// the kept resources belong to a started environment, so that a deactivation tears them down
+		s.applyObservedStatus(env)
+		if !isStartedStatus(env.Status) {
+			env.Status = model.STOPPED_STATUS
+		}
+		utils.Logger.Warn("keeping the resources created for env %s/%s for debugging, deactivate the environment once done", env.Workspace, env.Name)
+		return err
+	}
+
+	utils.Logger.Info("rolling back env %s/%s activation", env.Workspace, env.Name)
+	if rollbackErr := journal.rollback(cli); rollbackErr != nil {
+		err = fmt.Errorf("%v, %v", err, rollbackErr)
+	}
+	s.applyObservedStatus(env)
+	if isStartedStatus(env.Status) {
+		utils.Logger.Warn("containers of env %s/%s that predate the activation are still there, deactivate the environment to remove them", env.Workspace, env.Name)
+	}
+	return err
+}
+
+// applyObservedStatus saves the service statuses docker reports after a failed activation, the environment is
+// considered inactive when they can't be observed
+func (s DockerSynchronizationService) applyObservedStatus(env *model.Environment) {
+
+	ApplyStatus(env, model.INACTIVE_STATUS)
+	statuses, err := s.Observe(env)
+	if err != nil {
+		utils.Logger.Warn("failed to observe env %s/%s containers : %v", env.Workspace, env.Name, err)
+		return
+	}
+	for _, status := range statuses {
+		if service := env.Services[status.Service]; service != nil {
+			service.Status = status.Status
+		}
+	}
+	env.Status = getObservedEnvironmentStatus(statuses)
+}
+
+// Plan synchronizes the environment against an in-memory copy of the workspace docker daemon and returns the steps
+// an activation would take, neither the daemon nor the workspace are changed
+func (s DockerSynchronizationService) Plan(env *model.Environment) ([]string, error) {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	runtime, err := NewMemoryRuntimeFrom(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	// synchronizing writes statuses, host ports and defaults into the environment, the plan works on its own copy
+	planned, err := copyEnvironment(env)
+	if err != nil {
+		return nil, err
+	}
+	// a plan is recorded one service at a time so that its steps come out in a stable order
+	planned.Parallelism = 1
+	err = synchronize(ctx, runtime, planHost{runtime: runtime}, planned, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.Plan(), nil
+}
+
+// copyEnvironment deep copies the environment and its services, json keeps nil and empty values apart the same way
+// the container spec hash does, so the copy reconciles exactly like the original
+func copyEnvironment(env *model.Environment) (*model.Environment, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy environment %s : %v", env.Name, err)
+	}
+	copied := &model.Environment{}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, fmt.Errorf("failed to copy environment %s : %v", env.Name, err)
+	}
+	return copied, nil
+}
+
+// synchronize brings the environment containers in line with its spec, the created resources are recorded in the journal
+func synchronize(ctx context.Context, cli ContainerRuntime, host HostRunner, env *model.Environment, journal *activationJournal) error {
+
+	allocation := utils.Logger.GetProgressAllocation(env.Name)
+
+	waves, err := GetServiceWaves(env, "perun-db")
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s, invalid service dependencies : %v", env.Name, err)
+	}
+
+	if err := checkRemoteDaemon(cli, env); err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+
+	if err := checkNetworkAliases(ctx, cli, env); err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+
+	targetNetworkID, err := ensureNetwork(ctx, cli, env, journal)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+	err = removeOrphanContainers(ctx, cli, env, existing)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+
+	err = allocateHostPorts(ctx, cli, env, existing)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+	}
+
+	parallelism := getParallelism(env)
+	allServices := make([]*model.Service, 0, len(env.Services))
+	for _, service := range env.Services {
+		allServices = append(allServices, service)
+	}
+	sort.Slice(allServices, func(i, j int) bool {
+		return allServices[i].Name < allServices[j].Name
+	})
+
+	daemonPlatform, err := GetDaemonPlatform(ctx, cli)
+	if err != nil {
+		utils.Logger.Warn("failed to detect docker daemon platform, defaulting to %s : %v", DEFAULT_PLATFORM, err)
+		daemonPlatform = DEFAULT_PLATFORM
+	}
+
+	// pull and build all images upfront, containers are started later on following the dependency waves
+	images := make(map[string]*serviceImage)
+	var imagesLock sync.Mutex
+	err = runInParallel(allServices, parallelism, func(service *model.Service) error {
+		image, err := prepareServiceImage(ctx, cli, host, env, service, daemonPlatform)
 		if err != nil {
 			return err
 		}
-
-		targetNetworkID = resp.ID
+		imagesLock.Lock()
+		images[service.Name] = image
+		imagesLock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to synchronize env %s, failed to prepare service images : %v", env.Name, err)
 	}
 
 	// load db container first
 	dbService := env.Services["perun-db"]
 	if dbService != nil {
-		loadService(ctx, cli, targetNetworkID, env, dbService)
-		time.Sleep(15 * time.Second)
-		if dbService.Build.Type == "db" {
-			dbType := dbService.Build.Params["type"]
-			dbURL := dbService.Build.Params["url"]
-			targetDBURL := dbService.Build.Params["target-url"]
-
-			location, err := getDumpLocation(env, dbService)
+		if dbService.Run.HealthCheck == nil && dbService.Build != nil {
+			dbService.Run.HealthCheck = GetDBHealthCheck(dbService.Build.Params["type"])
+		}
+		containerID, created, err := loadService(ctx, cli, targetNetworkID, env, dbService, images[dbService.Name], existing[dbService.Name], journal)
+		if err != nil {
+			return fmt.Errorf("failed to synchronize env %s, failed to load service %s : %v", env.Name, dbService.Name, err)
+		}
+		err = WaitForService(ctx, cli, containerID, dbService)
+		if err != nil {
+			return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
+		}
+		if created {
+			err = runPostRunSteps(ctx, cli, containerID, dbService)
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to synchronize env %s : %v", env.Name, err)
 			}
-			var dumper DatabaseCopy
-			if dbType == "mysql" {
-
-				dumper = MySQLCopy{
-					URL:         dbURL,
-					TargetFile:  location,
-					TargetDBURL: targetDBURL,
-				}
-
-			} else if dbType == "postgres" {
-
-				dumper = PostgresCopy{
-					URL:         dbURL,
-					TargetFile:  location,
-					TargetDBURL: targetDBURL,
-				}
-
-			} else {
-				utils.Logger.Warn("cannot load db, unsupported db type %s", dbType)
-			}
-
-			if dumper != nil {
-				err := dumper.Copy()
-				if err != nil {
-					return err
-				}
+		}
+		// the db is only copied into a newly created container, a kept one holds the local data already
+		if created {
+			if err := copyDatabase(host, env, dbService); err != nil {
+				return err
 			}
 		}
 	}
@@ -119,15 +271,26 @@ func (s DockerSynchronizationService) Synchronize(env *model.Environment) error
 	if len(env.Services) > 0 {
 		increment = allocation / len(env.Services)
 	}
-	for _, service := range env.Services {
+	for i, wave := range waves {
 
-		if service.Name == "perun-db" {
-			continue
+		utils.Logger.Info("starting services wave %d/%d of env %s/%s", i+1, len(waves), env.Workspace, env.Name)
+		err = runInParallel(wave, parallelism, func(service *model.Service) error {
+			containerID, created, err := loadService(ctx, cli, targetNetworkID, env, service, images[service.Name], existing[service.Name], journal)
+			if err != nil {
+				return err
+			}
+			utils.Logger.Increment(increment, "")
+			err = WaitForService(ctx, cli, containerID, service)
+			if err != nil || !created {
+				return err
+			}
+			// post-run steps only run once, right after the container was created
+			return runPostRunSteps(ctx, cli, containerID, service)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to synchronize env %s, failed to load services : %v", env.Name, err)
 		}
 
-		loadService(ctx, cli, targetNetworkID, env, service)
-		utils.Logger.Increment(increment, "")
-
 	}
 
 	// go ContainerEvents(cli)
@@ -136,22 +299,65 @@ func (s DockerSynchronizationService) Synchronize(env *model.Environment) error
 
 }
 
-func loadService(ctx context.Context, cli *client.Client, targetNetworkID string, env *model.Environment, service *model.Service) error {
+// copyDatabase copies the remote database the perun db service was imported from into its container
+func copyDatabase(host HostRunner, env *model.Environment, dbService *model.Service) error {
 
-	runConfig := service.Run
-	containerID := ""
-	imageName := ""
+	if dbService.Build == nil || dbService.Build.Type != "db" {
+		return nil
+	}
+	dbType := dbService.Build.Params["type"]
+	dbURL := dbService.Build.Params["url"]
+	targetDBURL := getDBTargetURL(dbService)
 
-	if service.Build != nil && service.Build.Type == "dockerfile" {
-		//TODO build custom container first
-		//docker file support?
+	location, err := getDumpLocation(env, dbService)
+	if err != nil {
+		return err
+	}
+	var dumper DatabaseCopy
+	if dbType == "mysql" {
+
+		dumper = MySQLCopy{
+			URL:         dbURL,
+			TargetFile:  location,
+			TargetDBURL: targetDBURL,
+		}
+
+	} else if dbType == "postgres" {
+
+		dumper = PostgresCopy{
+			URL:         dbURL,
+			TargetFile:  location,
+			TargetDBURL: targetDBURL,
+		}
+
+	} else {
+		utils.Logger.Warn("cannot load db, unsupported db type %s", dbType)
+	}
+
+	if dumper == nil {
+		return nil
+	}
+	return host.CopyDatabase(dbService, dbType, dumper)
+}
+
+type serviceImage struct {
+	Name     string
+	Platform string
+}
+
+// prepareServiceImage resolves the service image, building or pulling it as needed, and returns the image to run
+func prepareServiceImage(ctx context.Context, cli ContainerRuntime, host HostRunner, env *model.Environment, service *model.Service, daemonPlatform string) (*serviceImage, error) {
+
+	imageName := ""
+	platform := GetPlatformOverride(env, service)
+	if platform == "" {
+		platform = daemonPlatform
 	}
 
-	volumeLocalPath := ""
 	switch service.Type {
 	case "local":
 		imageName = service.Params["image"] //in case an image was supplied for a local setup , we load that image. location will be used for debug capability
-		if imageName == "" {
+		if imageName == "" && !isDockerfileBuild(service) {
 			switch service.Params["source"] {
 			case "python":
 				imageName = "python"
@@ -161,17 +367,16 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 				//TODO analyze local code - should move that into import flow .. import will fetch git, analyze and set attributes for user to validate
 				_, err := analyzeCodeSource(service.Params["location"])
 				if err != nil {
-					return err
+					return nil, err
 				}
 			default:
-				return fmt.Errorf("unsupported source type %s", service.Params["source"])
+				return nil, fmt.Errorf("unsupported source type %s", service.Params["source"])
 			}
 
 			version := service.Params["version"]
 			if version != "" {
 				imageName += ":" + version
 			}
-			volumeLocalPath = service.Params["location"]
 		}
 
 	case "docker":
@@ -179,58 +384,91 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 		imageName = service.Params["image"]
 
 	case "git":
-		//TODO code fetch ?
 		//TODO code source analysis
-
-		return fmt.Errorf("not supported service type %s", service.Type)
+		builtImage, err := host.BuildGitImage(ctx, cli, env, service, platform)
+		if err != nil {
+			return nil, err
+		}
+		imageName = builtImage
 
 	default:
-		return fmt.Errorf("not supported service type %s", service.Type)
+		return nil, fmt.Errorf("not supported service type %s", service.Type)
 	}
 
-	pullOptions := types.ImagePullOptions{
-		Platform: "linux/amd64",
-	}
+	// git service images were already built out of the fetched source
+	if service.Type != "git" {
+		if isDockerfileBuild(service) {
+			builtImage, err := BuildServiceImage(ctx, cli, env, service, "", platform)
+			if err != nil {
+				return nil, err
+			}
+			imageName = builtImage
+		} else {
+			resolvedPlatform, err := resolveImagePlatform(ctx, cli, env, service, imageName, daemonPlatform)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve service %s platform : %v", service.Name, err)
+			}
+			platform = resolvedPlatform
 
-	authConfig := service.ContainerRegistry
-	if authConfig == nil {
-		authConfig = env.ContainerRegistry
-	}
-	if authConfig != nil {
-		encodedJSON, err := json.Marshal(types.AuthConfig{
-			Username:      authConfig.Username,
-			Password:      authConfig.Password,
-			RegistryToken: authConfig.Token,
-		})
-		if err != nil {
-			return err
+			err = pullServiceImage(ctx, cli, env, service, imageName, platform)
+			if err != nil {
+				return nil, err
+			}
 		}
-		authStr := base64.URLEncoding.EncodeToString(encodedJSON)
-		pullOptions.RegistryAuth = authStr
-
 	}
 
-	reader, err := cli.ImagePull(ctx, imageName, pullOptions)
-	if err != nil {
-		return err
+	for _, step := range service.PreRun {
+		if step.Image != "" {
+			err := pullServiceImage(ctx, cli, env, service, step.Image, platform)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	io.Copy(utils.Logger.GetOutput(), reader)
+	return &serviceImage{Name: imageName, Platform: platform}, nil
+}
+
+// loadService reconciles the service container against the desired spec, an up to date container is kept as is
+// while an outdated one is replaced by a newly created container, created reports whether a new container was started
+func loadService(ctx context.Context, cli ContainerRuntime, targetNetworkID string, env *model.Environment, service *model.Service, image *serviceImage, existing []types.Container, journal *activationJournal) (string, bool, error) {
+
+	runConfig := service.Run
+	containerID := ""
+	imageName := image.Name
+
+	volumeLocalPath := ""
+	if service.Type == "local" && service.Params["image"] == "" && !isDockerfileBuild(service) {
+		volumeLocalPath = service.Params["location"]
+	}
 
 	config := &container.Config{
 
-		Image: imageName,
-		Env:   GetEnVars(service.Run.EnVars),
-		// WorkingDir: "/app",
-		Labels: map[string]string{"provider": "perun", "provider-mode": "sync", "perun-workspace": env.Workspace, "perun-env": env.Name, "perun-env-target": "docker", "perun-service": service.Name},
+		Image:  imageName,
+		Env:    GetEnVars(service.Run.EnVars),
+		Labels: map[string]string{"provider": "perun", "provider-mode": "sync", "perun-workspace": env.Workspace, "perun-env": env.Name, "perun-env-target": "docker", "perun-service": service.Name, "perun-network": GetNetworkName(env)},
+	}
+
+	healthConfig, err := GetHealthConfig(runConfig.HealthCheck)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid health check for service %s : %v", service.Name, err)
+	}
+	config.Healthcheck = healthConfig
+
+	resources, err := GetContainerResources(service)
+	if err != nil {
+		return "", false, err
 	}
 
 	hostConfig := &container.HostConfig{
 		Runtime:    "runc",
 		AutoRemove: false,
-		RestartPolicy: container.RestartPolicy{
-			MaximumRetryCount: 10,
-		},
+		Resources:  resources,
+	}
+
+	err = applyRuntimeOptions(service, config, hostConfig)
+	if err != nil {
+		return "", false, err
 	}
 
 	exposedPortsArr := []string{}
@@ -238,7 +476,7 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 	if len(service.Run.Ports) > 0 {
 		for _, port := range service.Run.Ports {
 			if port.Exposed {
-				exposedPortsArr = append(exposedPortsArr, port.HostPort+":"+port.Port)
+				exposedPortsArr = append(exposedPortsArr, GetHostPort(port)+":"+port.Port)
 			} else {
 				portsArr = append(portsArr, port.Port)
 			}
@@ -263,45 +501,8 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 
 	}
 
-	if runConfig.Cmd != "" {
-
-		cmmnd := []string{"/bin/sh", "-c"}
-
-		generaterdCmmnd := ""
-		if len(service.PreRun) > 0 {
-
-			for _, pc := range service.PreRun {
-
-				if imageName == "python" && strings.HasSuffix(pc.Cmd, ".py") {
-					generaterdCmmnd += "python "
-				}
-				generaterdCmmnd += pc.Cmd
-				if len(pc.Args) > 0 {
-					generaterdCmmnd += " " + strings.Join(pc.Args, " ")
-				}
-				generaterdCmmnd += " && "
-
-			}
-
-		}
-
-		if imageName == "python" && strings.HasSuffix(runConfig.Cmd, ".py") {
-			generaterdCmmnd += "python "
-		}
-
-		generaterdCmmnd += runConfig.Cmd
-
-		if len(runConfig.Args) > 0 {
-			generaterdCmmnd += " " + strings.Join(runConfig.Args, " ")
-		}
-
-		if strings.HasPrefix(runConfig.Cmd, "/bin/sh") { //TODO minimize whitespace for correct split
-			cmmnd = strings.SplitN(generaterdCmmnd, " ", 3)
-		} else {
-			cmmnd = append(cmmnd, generaterdCmmnd)
-		}
-
-		config.Cmd = cmmnd
+	if cmd := GetContainerCommand(service); len(cmd) > 0 {
+		config.Cmd = cmd
 	}
 
 	hostConfig.Mounts = []mount.Mount{}
@@ -316,15 +517,19 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 	}
 
 	if len(service.Run.Mounts) > 0 {
-		for _, serviceMount := range service.Run.Mounts {
-			prpFilesLocation, err := getPropertiesFilesLocation(env, service, serviceMount)
-			if err != nil {
-				return err
+		mountNames := make([]string, 0, len(service.Run.Mounts))
+		for mountName := range service.Run.Mounts {
+			mountNames = append(mountNames, mountName)
+		}
+		sort.Strings(mountNames)
+		for _, mountName := range mountNames {
+			serviceMount := service.Run.Mounts[mountName]
+			if serviceMount.Name == "" {
+				serviceMount.Name = mountName
 			}
-			mountElem := mount.Mount{
-				Type:   mount.TypeBind,
-				Source: prpFilesLocation,
-				Target: serviceMount.Path,
+			mountElem, err := getServiceMount(ctx, cli, env, service, serviceMount, journal)
+			if err != nil {
+				return "", false, err
 			}
 
 			hostConfig.Mounts = append(hostConfig.Mounts, mountElem)
@@ -332,40 +537,173 @@ func loadService(ctx context.Context, cli *client.Client, targetNetworkID string
 
 	}
 
-	plt := &v1.Platform{
-		Architecture: "amd64",
-		OS:           "linux",
+	plt, err := ParsePlatform(image.Platform)
+	if err != nil {
+		return "", false, err
 	}
 
-	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, plt, env.Name+"-"+service.Name)
+	imageInspect, _, err := cli.ImageInspectWithRaw(ctx, imageName)
 	if err != nil {
-		return err
+		return "", false, fmt.Errorf("failed to inspect service %s image %s : %v", service.Name, imageName, err)
+	}
+	specHash, err := getSpecHash(config, hostConfig, image.Platform, imageInspect.ID, service)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to compute service %s spec hash : %v", service.Name, err)
+	}
+	config.Labels[SPEC_HASH_LABEL] = specHash
+
+	var current *types.Container
+	for i, c := range existing {
+		if current == nil && c.Labels[SPEC_HASH_LABEL] == specHash && containerName(c) == GetContainerName(env, service.Name) {
+			current = &existing[i]
+			continue
+		}
+		utils.Logger.Info("service %s container %s is outdated, recreating it", service.Name, containerName(c))
+		if err := removeContainer(ctx, cli, c); err != nil {
+			return "", false, err
+		}
+	}
+
+	if current != nil {
+		utils.Logger.Info("service %s container %s is up to date", service.Name, containerName(*current))
+		switch current.State {
+		case "running":
+		case "paused":
+			if err := cli.ContainerUnpause(ctx, current.ID); err != nil {
+				return "", false, err
+			}
+		default:
+			if err := cli.ContainerStart(ctx, current.ID, types.ContainerStartOptions{}); err != nil {
+				return "", false, err
+			}
+		}
+		service.Status = model.ACTIVE_STATUS
+		return current.ID, false, nil
+	}
+
+	err = runPreRunSteps(ctx, cli, targetNetworkID, env, service, config, hostConfig, plt)
+	if err != nil {
+		return "", false, err
+	}
+
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, plt, GetContainerName(env, service.Name))
+	if err != nil {
+		return "", false, err
 	}
 	containerID = resp.ID
+	journal.record(CONTAINER_RESOURCE, containerID, GetContainerName(env, service.Name))
 
 	cli.NetworkConnect(ctx, targetNetworkID, containerID, &network.EndpointSettings{
 		Aliases: []string{service.Name},
 	})
 
 	if err := cli.ContainerStart(ctx, containerID, types.ContainerStartOptions{}); err != nil {
-		return err
+		return "", false, err
 	}
 
 	service.Status = model.ACTIVE_STATUS
 
-	return nil
+	return containerID, true, nil
 
 }
 
+// Listen follows the events of every docker daemon the workspaces run on, the workspaces are looked up again
+// periodically so a daemon configured after the listener started is picked up as well
 func (s DockerSynchronizationService) Listen() error {
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	var lock sync.Mutex
+	listening := make(map[string]bool)
+	for {
+		workspaces, err := dockerClients.PersistenceService.ListWorkspaces()
+		if err != nil {
+			utils.Logger.Error("failed to list workspaces docker endpoints : %v", err)
+		}
+
+		clients, err := dockerClients.ForWorkspaces(workspaces)
+		if err != nil {
+			utils.Logger.Error("%v", err)
+		}
+		for host, cli := range clients {
+			lock.Lock()
+			if listening[host] {
+				lock.Unlock()
+				cli.Close()
+				continue
+			}
+			listening[host] = true
+			lock.Unlock()
+
+			go func(host string, cli *client.Client) {
+				listenDockerHost(host, cli)
+				// the listener is started again on the next lookup
+				lock.Lock()
+				delete(listening, host)
+				lock.Unlock()
+			}(host, cli)
+		}
+
+		time.Sleep(EVENTS_ENDPOINTS_REFRESH)
+	}
+}
+
+func listenDockerHost(host string, cli *client.Client) {
+
+	utils.Logger.Info("listening to docker host %s", host)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer cli.Close()
+
+	go func() {
+		err := NewLogCollector().Run(ctx, cli)
+		if err != nil && ctx.Err() == nil {
+			utils.Logger.Error("perun logs collector of docker host %s stopped : %v", host, err)
+		}
+	}()
+
+	err := ContainerEvents(cli)
 	if err != nil {
-		return err
+		utils.Logger.Error("perun events listener of docker host %s stopped : %v", host, err)
 	}
+}
 
-	ContainerEvents(cli)
+// checkRemoteDaemon fails when the environment needs files of this machine or reaches a service through a port published
+// on this machine while the docker daemon runs on another host, which neither sees those files nor publishes ports here
+func checkRemoteDaemon(cli ContainerRuntime, env *model.Environment) error {
+
+	host := cli.DaemonHost()
+	if isLocalDaemon(host) {
+		return nil
+	}
 
+	serviceNames := make([]string, 0, len(env.Services))
+	for name := range env.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+
+	for _, name := range serviceNames {
+		service := env.Services[name]
+		if service.Type == "local" && service.Params["image"] == "" && !isDockerfileBuild(service) {
+			return fmt.Errorf("service %s runs from local folder %s, which docker host %s can't see, build an image of it instead", service.Name, service.Params["location"], host)
+		}
+		if service.Build != nil && service.Build.Type == "db" {
+			return fmt.Errorf("service %s copies its database from this machine through a host port, which docker host %s doesn't publish here", service.Name, host)
+		}
+		if service.Run == nil {
+			continue
+		}
+		mountNames := make([]string, 0, len(service.Run.Mounts))
+		for mountName := range service.Run.Mounts {
+			mountNames = append(mountNames, mountName)
+		}
+		sort.Strings(mountNames)
+		for _, mountName := range mountNames {
+			mountType := service.Run.Mounts[mountName].Type
+			if mountType == "" || mountType == model.MOUNT_BIND {
+				return fmt.Errorf("service %s mount %s binds files of this machine, which docker host %s can't see, use a %s mount instead", service.Name, mountName, host, model.MOUNT_VOLUME)
+			}
+		}
+	}
 	return nil
 }
 
@@ -378,14 +716,8 @@ func getDumpLocation(env *model.Environment, service *model.Service) (string, er
 	}
 	workspacesDirectory := dirname + utils.WORKSPACES_HOME + env.Workspace
 
+	// the dump folder is created by the database copy itself
 	dumpsFileLocation := workspacesDirectory + "/" + service.Name + "/dump/"
-	err = os.MkdirAll(dumpsFileLocation, os.ModePerm)
-	if err != nil {
-		utils.Logger.Error("%v", err)
-		err = fmt.Errorf("failed to create dump folder: %v", err)
-		return "", err
-	}
-
 	return dumpsFileLocation + service.Name, nil
 }
 
@@ -442,7 +774,12 @@ func isDebugDisconnect(msg events.Message) bool {
 	return msg.Type == "container" && msg.Action == "destroy" && msg.Actor.Attributes["provider"] == "perun" && msg.Actor.Attributes["provider-mode"] == "debug"
 }
 
-func ContainerEvents(client *client.Client) error {
+// findOriginalContainer returns the synchronized container a debug container event swaps with
+func findOriginalContainer(cli ContainerRuntime, msg events.Message) (*types.Container, error) {
+	return FindServiceContainer(context.TODO(), cli, msg.Actor.Attributes["perun-workspace"], msg.Actor.Attributes["perun-env"], msg.Actor.Attributes["perun-service"])
+}
+
+func ContainerEvents(client ContainerRuntime) error {
 
 	utils.Logger.Info("Starting Docker Event listener")
 
@@ -463,15 +800,13 @@ func ContainerEvents(client *client.Client) error {
 			if isDebugConnect(msg) {
 				if msg.Actor.Attributes["perun-env-target"] == "docker" || msg.Actor.Attributes["perun-env-target"] == "local" {
 
-					originalContainerName := msg.Actor.Attributes["perun-env"] + "-" + msg.Actor.Attributes["perun-service"]
-
-					inspect, err := client.ContainerInspect(context.TODO(), originalContainerName)
+					original, err := findOriginalContainer(client, msg)
 					if err != nil {
 						utils.Logger.Error("%v", err)
 						return err
 					}
 
-					if inspect.State.Running {
+					if original != nil && original.State == "running" {
 						// stop running containerz
 
 						// err := client.NetworkDisconnect(context.TODO(), msg.Actor.Attributes["perun-workspace"], originalContainerName, true)
@@ -488,14 +823,14 @@ func ContainerEvents(client *client.Client) error {
 						// 	return err
 						// }
 
-						err = client.ContainerStop(context.TODO(), originalContainerName, nil)
+						err = client.ContainerStop(context.TODO(), original.ID, nil)
 
 						if err != nil {
 							utils.Logger.Error("%v", err)
 							return err
 						}
 					} else {
-						utils.Logger.Warn("Failed to find a running %s container", originalContainerName)
+						utils.Logger.Warn("Failed to find a running %s/%s/%s container", msg.Actor.Attributes["perun-workspace"], msg.Actor.Attributes["perun-env"], msg.Actor.Attributes["perun-service"])
 					}
 				}
 
@@ -503,15 +838,13 @@ func ContainerEvents(client *client.Client) error {
 
 				if msg.Actor.Attributes["perun-env-target"] == "docker" || msg.Actor.Attributes["perun-env-target"] == "local" {
 					// start stopped container
-					originalContainerName := msg.Actor.Attributes["perun-env"] + "-" + msg.Actor.Attributes["perun-service"]
-
-					inspect, err := client.ContainerInspect(context.TODO(), originalContainerName)
+					original, err := findOriginalContainer(client, msg)
 					if err != nil {
 						utils.Logger.Error("%v", err)
 						return err
 					}
 
-					if inspect.State.Status == "exited" {
+					if original != nil && original.State == "exited" {
 
 						// err := client.NetworkDisconnect(context.TODO(), msg.Actor.Attributes["perun-workspace"], originalContainerName, true)
 						// if err != nil {
@@ -527,13 +860,13 @@ func ContainerEvents(client *client.Client) error {
 						// 	return err
 						// }
 
-						err = client.ContainerStart(context.TODO(), originalContainerName, types.ContainerStartOptions{})
+						err = client.ContainerStart(context.TODO(), original.ID, types.ContainerStartOptions{})
 						if err != nil {
 							utils.Logger.Error("%v", err)
 							return err
 						}
 					} else {
-						utils.Logger.Warn("Failed to find a paused %s container", originalContainerName)
+						utils.Logger.Warn("Failed to find a paused %s/%s/%s container", msg.Actor.Attributes["perun-workspace"], msg.Actor.Attributes["perun-env"], msg.Actor.Attributes["perun-service"])
 					}
 				}
 
@@ -582,7 +915,7 @@ func (s DockerSynchronizationService) Unsynchronize(env *model.Environment) erro
 func (s DockerSynchronizationService) Destroy(env *model.Environment) error {
 
 	ctx := context.Background()
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := s.getRuntime(env.Workspace)
 	if err != nil {
 		return err
 	}
@@ -593,60 +926,26 @@ func (s DockerSynchronizationService) Destroy(env *model.Environment) error {
 		increment = allocation / len(env.Services)
 	}
 
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
+	if err != nil {
+		return err
+	}
+
 	for _, service := range env.Services {
 
 		utils.Logger.Info("destroying service %s/%s/%s", env.Workspace, env.Name, service.Name)
-		containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
-
-			Filters: filters.NewArgs(filters.Arg("name", env.Name+"-"+service.Name)),
-		})
-
-		if err != nil {
-			return err
-		}
-
-		containersCount := len(containers)
-		var containerToDelete types.Container
-		if containersCount != 1 {
-
-			if containersCount == 0 {
-
-				// log as warning .. nothing to destroy, no container found
-				continue
-
-			} else {
-				duplicates := 0
-				for _, container := range containers {
-					if container.Names[0] == "/"+env.Name+"-"+service.Name {
-						duplicates++
-						containerToDelete = container
-					}
-				}
-				if duplicates > 1 {
-					return fmt.Errorf("too many contianers with name %s", env.Name+"-"+service.Name)
+		for _, c := range existing[service.Name] {
+			if c.State == "running" {
+				utils.Logger.Info("stopping container %s with ID %s", containerName(c), c.ID)
+				if err := cli.ContainerStop(ctx, c.ID, nil); err != nil {
+					return err
 				}
 			}
-		} else {
-			containerToDelete = containers[0]
-		}
-
-		containerID := containerToDelete.ID
-
-		if err := cli.ContainerStop(ctx, containerID, nil); err != nil {
-			return err
-		}
-
-		utils.Logger.Info("stopping container %s with ID %s", env.Name+"-"+service.Name, containerID)
-
-		if err := cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
-			RemoveVolumes: true,
-			RemoveLinks:   false,
-			Force:         true,
-		}); err != nil {
-			return err
+			if err := removeContainer(ctx, cli, c); err != nil {
+				return err
+			}
 		}
-
-		utils.Logger.Info("removing container %s with ID %s", env.Name+"-"+service.Name, containerID)
+		delete(existing, service.Name)
 
 		service.Status = model.INACTIVE_STATUS
 
@@ -654,21 +953,16 @@ func (s DockerSynchronizationService) Destroy(env *model.Environment) error {
 
 	}
 
-	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
-		Filters: filters.NewArgs(filters.Arg("name", env.Workspace)),
-	})
-
-	networksCount := len(networks)
-	if networksCount != 1 {
-
-		return fmt.Errorf("too many networks with name %s", env.Workspace)
-
+	// containers of services removed from the environment since its last synchronization
+	for _, containers := range existing {
+		for _, c := range containers {
+			if err := removeContainer(ctx, cli, c); err != nil {
+				return err
+			}
+		}
 	}
 
-	targetNetworkID := networks[0].ID
-
-	cli.NetworkRemove(ctx, targetNetworkID)
-	return nil
+	return releaseNetwork(ctx, cli, env)
 
 }
 
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"main.go/model"
+)
+
+func getTestSyncEnvironment() *model.Environment {
+	return &model.Environment{
+		Name:      "testEnv",
+		Workspace: "testWS",
+		Services: map[string]*model.Service{
+			"api": {
+				Name:      "api",
+				Type:      "docker",
+				Params:    map[string]string{"image": "nginx:1.25"},
+				DependsOn: []string{"cache"},
+				Run: &model.RunConfig{
+					Mounts: map[string]model.Mount{"data": {Type: model.MOUNT_VOLUME, Path: "/data"}},
+				},
+				PostRun: []model.Command{{Cmd: model.ExecCommand{"nginx", "-t"}}},
+			},
+			"cache": {
+				Name:   "cache",
+				Type:   "docker",
+				Params: map[string]string{"image": "redis:7"},
+				Run:    &model.RunConfig{},
+			},
+		},
+	}
+}
+
+func TestPlan(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	plan, err := s.Plan(env)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"create network testWS-testEnv",
+		"pull image nginx:1.25 for platform linux/amd64",
+		"pull image redis:7 for platform linux/amd64",
+		"create container testWS-testEnv-cache from image redis:7",
+		"connect container testWS-testEnv-cache to network testWS-testEnv",
+		"start container testWS-testEnv-cache",
+		"create volume perun-testws-testenv-data",
+		"create container testWS-testEnv-api from image nginx:1.25",
+		"connect container testWS-testEnv-api to network testWS-testEnv",
+		"start container testWS-testEnv-api",
+		"exec nginx -t in container testWS-testEnv-api",
+	}, plan)
+	// planning changes nothing
+	assert.Len(t, runtime.Plan(), 0)
+
+	// the plan starts from what the daemon already runs
+	assert.Nil(t, s.Synchronize(env))
+	env.Services["cache"].Run.EnVars = []model.EnVar{{Key: "MODE", Value: "lru"}}
+	steps := len(runtime.Plan())
+	plan, err = s.Plan(env)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"remove container testWS-testEnv-cache",
+		"create container testWS-testEnv-cache from image redis:7",
+		"connect container testWS-testEnv-cache to network testWS-testEnv",
+		"start container testWS-testEnv-cache",
+	}, plan)
+	assert.Len(t, runtime.Plan(), steps)
+}
+
+func TestPlanKeepsEnvironment(t *testing.T) {
+	setTestLogger(t)
+
+	withDB := func() *model.Environment {
+		env := getTestSyncEnvironment()
+		env.Services["api"].Run.Ports = []model.Port{{Port: "80", HostPort: "auto"}}
+		env.Services["perun-db"] = &model.Service{
+			Name:   "perun-db",
+			Type:   "docker",
+			Params: map[string]string{"image": "mysql:8.0"},
+			Build:  &model.BuildConfig{Type: "db", Params: map[string]string{"type": "mysql", "url": "user:pass@tcp(remote:3306)/app"}},
+			Run:    &model.RunConfig{},
+		}
+		return env
+	}
+
+	// no assigned host port, status or health check default is written back
+	env := withDB()
+	plan, err := DockerSynchronizationService{Runtime: NewMemoryRuntime()}.Plan(env)
+	assert.Nil(t, err)
+	assert.Contains(t, plan, "copy mysql database into service perun-db")
+	assert.Equal(t, withDB(), env)
+}
+
+func TestPlanDaemonPlatform(t *testing.T) {
+	setTestLogger(t)
+
+	source := NewMemoryRuntime()
+	source.info = types.Info{OSType: "linux", Architecture: "aarch64"}
+
+	plan, err := DockerSynchronizationService{Runtime: source}.Plan(getTestSyncEnvironment())
+	assert.Nil(t, err)
+	assert.Contains(t, plan, "pull image redis:7 for platform linux/arm64")
+}
+
+func TestSynchronizeUpToDate(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	assert.Nil(t, s.Synchronize(env))
+	assert.Equal(t, model.ACTIVE_STATUS, env.Status)
+	steps := len(runtime.Plan())
+
+	// the second run finds every container up to date and changes nothing
+	assert.Nil(t, s.Synchronize(env))
+	assert.Len(t, runtime.Plan(), steps)
+
+	// a changed spec recreates the service container only
+	env.Services["cache"].Run.EnVars = []model.EnVar{{Key: "MODE", Value: "lru"}}
+	assert.Nil(t, s.Synchronize(env))
+	assert.Equal(t, []string{
+		"remove container testWS-testEnv-cache",
+		"create container testWS-testEnv-cache from image redis:7",
+		"connect container testWS-testEnv-cache to network testWS-testEnv",
+		"start container testWS-testEnv-cache",
+	}, runtime.Plan()[steps:])
+
+	containers, err := ListEnvironmentContainers(context.Background(), runtime, env)
+	assert.Nil(t, err)
+	assert.Len(t, containers, 2)
+}
+
+func TestActivateRollback(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime}
+
+	env := getTestSyncEnvironment()
+	env.Services["cache"].PullPolicy = model.PULL_NEVER
+	err := s.Activate(env, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, model.INACTIVE_STATUS, env.Status)
+
+	plan := runtime.Plan()
+	assert.Equal(t, "remove network testWS-testEnv", plan[len(plan)-1])
+	networks, err := runtime.NetworkList(context.Background(), types.NetworkListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, networks, 0)
+}
+
+func TestSynchronizeDBCopy(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	s := DockerSynchronizationService{Runtime: runtime, Host: planHost{runtime: runtime}}
+
+	env := getTestSyncEnvironment()
+	env.Services["perun-db"] = &model.Service{
+		Name:   "perun-db",
+		Type:   "docker",
+		Params: map[string]string{"image": "mysql:8.0"},
+		Build:  &model.BuildConfig{Type: "db", Params: map[string]string{"type": "mysql", "url": "user:pass@tcp(remote:3306)/app"}},
+		Run:    &model.RunConfig{},
+	}
+	assert.Nil(t, s.Synchronize(env))
+	assert.Contains(t, runtime.Plan(), "copy mysql database into service perun-db")
+	steps := len(runtime.Plan())
+
+	// the db container is kept as is, so is its data
+	assert.Nil(t, s.Synchronize(env))
+	assert.Len(t, runtime.Plan(), steps)
+}
+
+func TestSynchronizeRemoteDaemon(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := NewMemoryRuntime()
+	runtime.host = "tcp://build.example.com:2376"
+	s := DockerSynchronizationService{Runtime: runtime, Host: planHost{runtime: runtime}}
+
+	// volumes live on the daemon host
+	env := getTestSyncEnvironment()
+	assert.Nil(t, s.Synchronize(env))
+
+	env.Services["api"].Run.Mounts["config"] = model.Mount{Path: "/etc/nginx/conf.d"}
+	assert.EqualError(t, s.Synchronize(env), "failed to synchronize env testEnv : service api mount config binds files of this machine, which docker host tcp://build.example.com:2376 can't see, use a volume mount instead")
+
+	env = getTestSyncEnvironment()
+	env.Services["perun-db"] = &model.Service{
+		Name:   "perun-db",
+		Type:   "docker",
+		Params: map[string]string{"image": "mysql:8.0"},
+		Build:  &model.BuildConfig{Type: "db", Params: map[string]string{"type": "mysql", "url": "user:pass@tcp(remote:3306)/app"}},
+		Run:    &model.RunConfig{},
+	}
+	assert.EqualError(t, s.Synchronize(env), "failed to synchronize env testEnv : service perun-db copies its database from this machine through a host port, which docker host tcp://build.example.com:2376 doesn't publish here")
+}
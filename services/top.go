@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// ServiceStats is the resource usage of a service container
+type ServiceStats struct {
+	Service       string  `json:"service"`
+	Container     string  `json:"container"`
+	State         string  `json:"state"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetworkRx     uint64  `json:"network_rx"`
+	NetworkTx     uint64  `json:"network_tx"`
+	BlockRead     uint64  `json:"block_read"`
+	BlockWrite    uint64  `json:"block_write"`
+	RestartCount  int     `json:"restart_count"`
+}
+
+type statsTarget struct {
+	service      string
+	container    types.Container
+	restartCount int
+}
+
+// isStatsTarget tells whether the container shows up in the usage, a crash looping container is kept for its restart count
+func isStatsTarget(c types.Container) bool {
+	return c.State == "running" || c.State == "restarting"
+}
+
+// getStatsTargets lists the running and restarting environment containers along with their current restart count
+func getStatsTargets(ctx context.Context, cli ContainerRuntime, env *model.Environment) ([]statsTarget, error) {
+
+	existing, err := ListEnvironmentContainers(ctx, cli, env)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]statsTarget, 0, len(existing))
+	for name, containers := range existing {
+		for _, c := range containers {
+			if !isStatsTarget(c) {
+				continue
+			}
+			inspect, err := cli.ContainerInspect(ctx, c.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inspect service %s container : %v", name, err)
+			}
+			targets = append(targets, statsTarget{service: name, container: c, restartCount: inspect.RestartCount})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].service != targets[j].service {
+			return targets[i].service < targets[j].service
+		}
+		return containerName(targets[i].container) < containerName(targets[j].container)
+	})
+	return targets, nil
+}
+
+// GetEnvironmentStats samples the resource usage of the running environment containers once
+func GetEnvironmentStats(env *model.Environment) ([]*ServiceStats, error) {
+
+	ctx := context.Background()
+	cli, err := GetDockerClient(env.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := getStatsTargets(ctx, cli, env)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no running or restarting container found for environment %s/%s", env.Workspace, env.Name)
+	}
+
+	stats := make([]*ServiceStats, len(targets))
+	errs := make(ServiceErrors)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target statsTarget) {
+			defer wg.Done()
+			if target.container.State != "running" {
+				// a restarting container has no usage to sample
+				stats[i] = getServiceStats(target, &types.StatsJSON{})
+				return
+			}
+			// a non streamed sample lets docker fill in the previous cpu reading, which the cpu percentage needs
+			resp, err := cli.ContainerStats(ctx, target.container.ID, false)
+			if err == nil {
+				defer resp.Body.Close()
+				var sample types.StatsJSON
+				err = json.NewDecoder(resp.Body).Decode(&sample)
+				stats[i] = getServiceStats(target, &sample)
+			}
+			if err != nil {
+				lock.Lock()
+				errs[target.service] = err
+				lock.Unlock()
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return stats, nil
+}
+
+// StreamEnvironmentStats streams the resource usage of the running environment containers,
+// render is called with the latest sample of every container on each interval until the context is done
+func StreamEnvironmentStats(ctx context.Context, env *model.Environment, interval time.Duration, render func([]*ServiceStats)) error {
+
+	cli, err := GetDockerClient(env.Workspace)
+	if err != nil {
+		return err
+	}
+	return streamEnvironmentStats(ctx, cli, env, interval, render)
+}
+
+// statsStream holds the latest sample streamed for a container, a restarting container is not streamed and has a zero sample
+type statsStream struct {
+	target statsTarget
+	sample *types.StatsJSON
+	cancel context.CancelFunc
+}
+
+// streamEnvironmentStats refreshes the targets on each interval, so restart counts stay current,
+// containers started afterwards are streamed and the streams of stopped or removed containers are closed
+func streamEnvironmentStats(ctx context.Context, cli ContainerRuntime, env *model.Environment, interval time.Duration, render func([]*ServiceStats)) error {
+
+	targets, err := getStatsTargets(ctx, cli, env)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no running or restarting container found for environment %s/%s", env.Workspace, env.Name)
+	}
+
+	streams := make(map[string]*statsStream)
+	var lock sync.Mutex
+	defer func() {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, stream := range streams {
+			stream.stop()
+		}
+	}()
+
+	refresh := func(targets []statsTarget) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		current := make(map[string]bool)
+		for _, target := range targets {
+			current[target.container.ID] = true
+			stream, ok := streams[target.container.ID]
+			if !ok {
+				stream = &statsStream{}
+				streams[target.container.ID] = stream
+			}
+			stream.target = target
+			if target.container.State != "running" {
+				stream.stop()
+				stream.sample = &types.StatsJSON{}
+				continue
+			}
+			if stream.cancel == nil {
+				streamCtx, cancel := context.WithCancel(ctx)
+				stream.cancel = cancel
+				go readStats(streamCtx, cli, stream, &lock)
+			}
+		}
+		for id, stream := range streams {
+			if !current[id] {
+				stream.stop()
+				delete(streams, id)
+			}
+		}
+	}
+	refresh(targets)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			targets, err := getStatsTargets(ctx, cli, env)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				utils.Logger.Error("failed to refresh environment %s/%s containers : %v", env.Workspace, env.Name, err)
+			} else {
+				refresh(targets)
+			}
+
+			lock.Lock()
+			stats := make([]*ServiceStats, 0, len(streams))
+			for _, stream := range streams {
+				if stream.sample != nil {
+					stats = append(stats, getServiceStats(stream.target, stream.sample))
+				}
+			}
+			lock.Unlock()
+			sort.Slice(stats, func(i, j int) bool {
+				if stats[i].Service != stats[j].Service {
+					return stats[i].Service < stats[j].Service
+				}
+				return stats[i].Container < stats[j].Container
+			})
+			render(stats)
+		}
+	}
+}
+
+// stop closes the container stats stream, if any
+func (stream *statsStream) stop() {
+	if stream.cancel != nil {
+		stream.cancel()
+		stream.cancel = nil
+	}
+}
+
+// readStats keeps the latest sample of the container stats stream until the stream context is cancelled
+func readStats(ctx context.Context, cli ContainerRuntime, stream *statsStream, lock *sync.Mutex) {
+
+	lock.Lock()
+	target := stream.target
+	lock.Unlock()
+
+	resp, err := cli.ContainerStats(ctx, target.container.ID, true)
+	if err != nil {
+		if ctx.Err() == nil {
+			utils.Logger.Error("failed to stream service %s stats : %v", target.service, err)
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var sample types.StatsJSON
+		if err := decoder.Decode(&sample); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				utils.Logger.Error("failed to read service %s stats : %v", target.service, err)
+			}
+			return
+		}
+		lock.Lock()
+		// a closed stream no longer owns the sample
+		if ctx.Err() == nil {
+			stream.sample = &sample
+		}
+		lock.Unlock()
+	}
+}
+
+func getServiceStats(target statsTarget, sample *types.StatsJSON) *ServiceStats {
+
+	stats := &ServiceStats{
+		Service:      target.service,
+		Container:    containerName(target.container),
+		State:        target.container.State,
+		CPUPercent:   getCPUPercent(sample),
+		MemoryUsage:  getMemoryUsage(sample.MemoryStats),
+		MemoryLimit:  sample.MemoryStats.Limit,
+		RestartCount: target.restartCount,
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+	for _, network := range sample.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+	for _, entry := range sample.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats
+}
+
+// getCPUPercent computes the cpu usage between the two readings of the sample, the same way docker stats does
+func getCPUPercent(sample *types.StatsJSON) float64 {
+	cpuDelta := float64(sample.CPUStats.CPUUsage.TotalUsage) - float64(sample.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(sample.CPUStats.SystemUsage) - float64(sample.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(sample.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(sample.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+// getMemoryUsage leaves the page cache out of the memory usage, it can be reclaimed at any time
+func getMemoryUsage(memory types.MemoryStats) uint64 {
+	// cgroup v1 reports the cache as total_inactive_file, cgroup v2 as inactive_file
+	cache, ok := memory.Stats["total_inactive_file"]
+	if !ok {
+		cache = memory.Stats["inactive_file"]
+	}
+	if cache < memory.Usage {
+		return memory.Usage - cache
+	}
+	return memory.Usage
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetServiceStats(t *testing.T) {
+	sample := &types.StatsJSON{}
+	sample.CPUStats.CPUUsage.TotalUsage = 300
+	sample.CPUStats.SystemUsage = 2000
+	sample.CPUStats.OnlineCPUs = 4
+	sample.PreCPUStats.CPUUsage.TotalUsage = 100
+	sample.PreCPUStats.SystemUsage = 1000
+	sample.MemoryStats = types.MemoryStats{Usage: 300, Limit: 1000, Stats: map[string]uint64{"inactive_file": 100}}
+	sample.Networks = map[string]types.NetworkStats{"eth0": {RxBytes: 10, TxBytes: 20}, "eth1": {RxBytes: 1, TxBytes: 2}}
+	sample.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{{Op: "Read", Value: 5}, {Op: "write", Value: 7}, {Op: "Total", Value: 12}}
+
+	stats := getServiceStats(statsTarget{service: "api", container: types.Container{Names: []string{"/ws-env-api"}}, restartCount: 2}, sample)
+
+	assert.Equal(t, "api", stats.Service)
+	assert.Equal(t, "ws-env-api", stats.Container)
+	assert.Equal(t, 80.0, stats.CPUPercent)
+	assert.Equal(t, uint64(200), stats.MemoryUsage)
+	assert.Equal(t, 20.0, stats.MemoryPercent)
+	assert.Equal(t, uint64(11), stats.NetworkRx)
+	assert.Equal(t, uint64(22), stats.NetworkTx)
+	assert.Equal(t, uint64(5), stats.BlockRead)
+	assert.Equal(t, uint64(7), stats.BlockWrite)
+	assert.Equal(t, 2, stats.RestartCount)
+}
+
+func TestGetCPUPercentWithoutPreviousReading(t *testing.T) {
+	sample := &types.StatsJSON{}
+	sample.CPUStats.CPUUsage.TotalUsage = 300
+	sample.CPUStats.SystemUsage = 2000
+	assert.Equal(t, 0.0, getCPUPercent(sample))
+}
+
+// restartingRuntime reports one more restart of every container on each inspection
+type restartingRuntime struct {
+	*MemoryRuntime
+	restarts int
+}
+
+func (r *restartingRuntime) ContainerInspect(ctx context.Context, container string) (types.ContainerJSON, error) {
+	inspect, err := r.MemoryRuntime.ContainerInspect(ctx, container)
+	if err == nil {
+		r.restarts++
+		inspect.RestartCount = r.restarts
+	}
+	return inspect, err
+}
+
+func TestStreamEnvironmentStats(t *testing.T) {
+	setTestLogger(t)
+
+	runtime := &restartingRuntime{MemoryRuntime: NewMemoryRuntime()}
+	env := getTestSyncEnvironment()
+	env.Services["api"].PostRun = nil
+	assert.Nil(t, DockerSynchronizationService{Runtime: runtime}.Synchronize(env))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	renders := make([][]*ServiceStats, 0)
+	err := streamEnvironmentStats(ctx, runtime, env, 10*time.Millisecond, func(stats []*ServiceStats) {
+		renders = append(renders, stats)
+		switch len(renders) {
+		case 1:
+			// wait for the first samples to come in
+			if len(stats) < 2 {
+				renders = renders[:0]
+			}
+		case 2:
+			runtime.setContainerState(GetContainerName(env, "cache"), "crash", "restarting")
+		case 3:
+			runtime.ContainerStop(ctx, GetContainerName(env, "cache"), nil)
+		case 4:
+			cancel()
+		}
+	})
+	assert.Nil(t, err)
+
+	assert.Len(t, renders, 4)
+	assert.Equal(t, []string{"api", "cache"}, []string{renders[0][0].Service, renders[0][1].Service})
+	assert.Equal(t, "running", renders[0][1].State)
+	// the restart count is read again on each refresh
+	assert.Greater(t, renders[1][0].RestartCount, renders[0][0].RestartCount)
+	// a crash looping container stays listed with its restart count and no usage
+	assert.Len(t, renders[2], 2)
+	assert.Equal(t, "restarting", renders[2][1].State)
+	assert.Greater(t, renders[2][1].RestartCount, renders[1][1].RestartCount)
+	assert.Equal(t, 0.0, renders[2][1].CPUPercent)
+	// the stopped container is no longer streamed
+	assert.Len(t, renders[3], 1)
+	assert.Equal(t, "api", renders[3][0].Service)
+}
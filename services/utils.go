@@ -58,7 +58,7 @@ func GetDBService(dbURL string, dbType string, dbVersion string) (*model.Service
 		ports = []model.Port{{
 
 			Port:     "5432",
-			HostPort: "5432",
+			HostPort: AUTO_HOST_PORT,
 			Exposed:  true,
 		}}
 		targetURL = dbUser + ":" + dbPass + "@localhost:5432" + "/" + dbName
@@ -85,7 +85,7 @@ func GetDBService(dbURL string, dbType string, dbVersion string) (*model.Service
 		ports = []model.Port{{
 
 			Port:     "3306",
-			HostPort: "3306",
+			HostPort: AUTO_HOST_PORT,
 			Exposed:  true,
 		}}
 	} else {
@@ -108,8 +108,9 @@ func GetDBService(dbURL string, dbType string, dbVersion string) (*model.Service
 			"image": image,
 		},
 		Run: &model.RunConfig{
-			EnVars: dbEnVars,
-			Ports:  ports,
+			EnVars:      dbEnVars,
+			Ports:       ports,
+			HealthCheck: GetDBHealthCheck(dbType),
 		},
 	}, nil
 }
@@ -151,3 +152,16 @@ func GetSQLEnVars(dbURL string) (string, string, string, string, error) {
 
 	return u.Host, dbName, dbUser, dbPassword, nil
 }
+
+// getDBTargetURL returns the perun db url as seen from the host, pointing at the host port the db port was published on
+func getDBTargetURL(dbService *model.Service) string {
+
+	targetURL := dbService.Build.Params["target-url"]
+	for _, port := range dbService.Run.Ports {
+		hostPort := GetHostPort(port)
+		if port.Exposed && hostPort != "" {
+			targetURL = strings.Replace(targetURL, "@localhost:"+port.Port+"/", "@localhost:"+hostPort+"/", 1)
+		}
+	}
+	return targetURL
+}
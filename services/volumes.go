@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	corev1 "k8s.io/api/core/v1"
+
+	"main.go/model"
+	"main.go/utils"
+)
+
+// GetVolumeName returns the docker volume backing a named service volume, volumes are scoped per workspace and environment
+func GetVolumeName(env *model.Environment, volumeName string) string {
+	return strings.ToLower("perun-" + env.Workspace + "-" + env.Name + "-" + volumeName)
+}
+
+func ensureVolume(ctx context.Context, cli ContainerRuntime, env *model.Environment, volumeName string, journal *activationJournal) (string, error) {
+
+	name := GetVolumeName(env, volumeName)
+	_, err := cli.VolumeInspect(ctx, name)
+	if err == nil {
+		return name, nil
+	}
+	if !client.IsErrNotFound(err) {
+		return "", fmt.Errorf("failed to inspect volume %s : %v", name, err)
+	}
+
+	utils.Logger.Info("creating volume %s", name)
+	_, err = cli.VolumeCreate(ctx, volumetypes.VolumeCreateBody{
+		Name: name,
+		Labels: map[string]string{
+			"provider":        "perun",
+			"perun-workspace": env.Workspace,
+			"perun-env":       env.Name,
+			"perun-volume":    volumeName,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume %s : %v", name, err)
+	}
+	journal.record(VOLUME_RESOURCE, name, name)
+
+	return name, nil
+}
+
+// getServiceMount converts a service mount into a docker mount, creating the backing named volume when needed
+func getServiceMount(ctx context.Context, cli ContainerRuntime, env *model.Environment, service *model.Service, serviceMount model.Mount, journal *activationJournal) (mount.Mount, error) {
+
+	switch serviceMount.Type {
+	case "", model.MOUNT_BIND:
+		location, err := getPropertiesFilesLocation(env, service, serviceMount)
+		if err != nil {
+			return mount.Mount{}, err
+		}
+		return mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   location,
+			Target:   serviceMount.Path,
+			ReadOnly: serviceMount.ReadOnly,
+		}, nil
+	case model.MOUNT_VOLUME:
+		name, err := ensureVolume(ctx, cli, env, serviceMount.Name, journal)
+		if err != nil {
+			return mount.Mount{}, err
+		}
+		return mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   name,
+			Target:   serviceMount.Path,
+			ReadOnly: serviceMount.ReadOnly,
+		}, nil
+	case model.MOUNT_TMPFS:
+		tmpfs := mount.Mount{
+			Type:     mount.TypeTmpfs,
+			Target:   serviceMount.Path,
+			ReadOnly: serviceMount.ReadOnly,
+		}
+		if serviceMount.Size != "" {
+			size, err := parseBytes(serviceMount.Size)
+			if err != nil {
+				return mount.Mount{}, fmt.Errorf("invalid size %s for service %s mount %s : %v", serviceMount.Size, service.Name, serviceMount.Name, err)
+			}
+			tmpfs.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: size}
+		}
+		return tmpfs, nil
+	default:
+		return mount.Mount{}, fmt.Errorf("unsupported mount type %s for service %s mount %s, expected one of %s, %s or %s", serviceMount.Type, service.Name, serviceMount.Name, model.MOUNT_BIND, model.MOUNT_VOLUME, model.MOUNT_TMPFS)
+	}
+}
+
+// PurgeVolumes removes the named volumes of the environment, or of the whole workspace when the environment name is empty
+func (s DockerSynchronizationService) PurgeVolumes(env *model.Environment) error {
+
+	ctx := context.Background()
+	cli, err := s.getRuntime(env.Workspace)
+	if err != nil {
+		return err
+	}
+
+	args := filters.NewArgs(
+		filters.Arg("label", "provider=perun"),
+		filters.Arg("label", "perun-workspace="+env.Workspace),
+	)
+	if env.Name != "" {
+		args.Add("label", "perun-env="+env.Name)
+	}
+
+	volumes, err := cli.VolumeList(ctx, args)
+	if err != nil {
+		return fmt.Errorf("failed to list volumes of env %s/%s : %v", env.Workspace, env.Name, err)
+	}
+
+	for _, volume := range volumes.Volumes {
+		utils.Logger.Info("removing volume %s", volume.Name)
+		if err := cli.VolumeRemove(ctx, volume.Name, false); err != nil {
+			return fmt.Errorf("failed to remove volume %s : %v", volume.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// getK8sDataMount maps a memory backed k8s emptyDir to a tmpfs mount, a disk backed one to a named volume private to
+// the service, as the emptyDir is private to its pod, and a persistent volume claim to a named volume
+func getK8sDataMount(serviceName string, volume corev1.Volume, volumeMount corev1.VolumeMount) model.Mount {
+
+	dataMount := model.Mount{
+		Name:     volume.Name,
+		Path:     volumeMount.MountPath,
+		ReadOnly: volumeMount.ReadOnly,
+	}
+
+	if volume.EmptyDir != nil {
+		if volume.EmptyDir.Medium != corev1.StorageMediumMemory {
+			dataMount.Type = model.MOUNT_VOLUME
+			dataMount.Name = serviceName + "-" + volume.Name
+			return dataMount
+		}
+		dataMount.Type = model.MOUNT_TMPFS
+		if volume.EmptyDir.SizeLimit != nil {
+			dataMount.Size = volume.EmptyDir.SizeLimit.String()
+		}
+		return dataMount
+	}
+
+	dataMount.Type = model.MOUNT_VOLUME
+	dataMount.Name = volume.PersistentVolumeClaim.ClaimName
+	dataMount.ReadOnly = dataMount.ReadOnly || volume.PersistentVolumeClaim.ReadOnly
+	return dataMount
+}
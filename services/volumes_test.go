@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetServiceMountTmpfs(t *testing.T) {
+
+	env := &model.Environment{Name: "testEnv", Workspace: "testWS"}
+	service := &model.Service{Name: "testService"}
+
+	dockerMount, err := getServiceMount(context.Background(), nil, env, service, model.Mount{Name: "cache", Type: model.MOUNT_TMPFS, Path: "/cache", Size: "64m"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, mount.Mount{Type: mount.TypeTmpfs, Target: "/cache", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 64 * 1024 * 1024}}, dockerMount)
+
+	_, err = getServiceMount(context.Background(), nil, env, service, model.Mount{Name: "data", Type: "nfs", Path: "/data"}, nil)
+	assert.EqualError(t, err, "unsupported mount type nfs for service testService mount data, expected one of bind, volume or tmpfs")
+
+	assert.Equal(t, "perun-testws-testenv-data", GetVolumeName(env, "data"))
+}
+
+func TestGetK8sDataMount(t *testing.T) {
+
+	sizeLimit := resource.MustParse("128Mi")
+	memoryDir := corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit}}}
+	assert.Equal(t, model.Mount{Name: "cache", Type: model.MOUNT_TMPFS, Path: "/cache", Size: "128Mi"},
+		getK8sDataMount("redis", memoryDir, corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+
+	// a disk backed emptyDir stays on disk
+	diskDir := corev1.Volume{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}}}
+	assert.Equal(t, model.Mount{Name: "redis-cache", Type: model.MOUNT_VOLUME, Path: "/cache"},
+		getK8sDataMount("redis", diskDir, corev1.VolumeMount{Name: "cache", MountPath: "/cache"}))
+
+	pvc := corev1.Volume{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "redis-data"}}}
+	assert.Equal(t, model.Mount{Name: "redis-data", Type: model.MOUNT_VOLUME, Path: "/data", ReadOnly: true},
+		getK8sDataMount("redis", pvc, corev1.VolumeMount{Name: "data", MountPath: "/data", ReadOnly: true}))
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"main.go/model"
+)
+
+const DEFAULT_PARALLELISM = 4
+
+// ServiceErrors collects the errors of an operation applied on several services, keyed by service name
+type ServiceErrors map[string]error
+
+func (e ServiceErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("service %s : %v", name, e[name]))
+	}
+	return strings.Join(messages, "; ")
+}
+
+func getParallelism(env *model.Environment) int {
+	if env.Parallelism > 0 {
+		return env.Parallelism
+	}
+	return DEFAULT_PARALLELISM
+}
+
+// runInParallel applies fn on all services with at most parallelism concurrent workers,
+// all services are processed and the failed ones are reported through ServiceErrors
+func runInParallel(services []*model.Service, parallelism int, fn func(service *model.Service) error) error {
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	errs := make(ServiceErrors)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+
+	for _, service := range services {
+		service := service
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := fn(service); err != nil {
+				lock.Lock()
+				errs[service.Name] = err
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
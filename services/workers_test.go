@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"main.go/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInParallel(t *testing.T) {
+
+	services := make([]*model.Service, 0)
+	for i := 0; i < 10; i++ {
+		services = append(services, &model.Service{Name: fmt.Sprintf("service-%d", i)})
+	}
+
+	var running, maxRunning int32
+	err := runInParallel(services, 3, func(service *model.Service) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			observed := atomic.LoadInt32(&maxRunning)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if service.Name == "service-2" || service.Name == "service-7" {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	assert.EqualError(t, err, "service service-2 : boom; service service-7 : boom")
+
+	errs, ok := err.(ServiceErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
-	"strings"
 
 	"main.go/model"
 	"main.go/utils"
@@ -32,10 +31,17 @@ type WorkspacesService interface {
 	GetWorkspace(name string) (*model.Workspace, error)
 	ListWorkspaces() ([]*model.Workspace, error)
 	DestroyWorkspace(name string) error
-	ActivateEnvironment(workspace string, environment string) error
+	ActivateEnvironment(workspace string, environment string, keepOnFailure bool) error
 	DeactivateEnvironment(workspace string, environment string) error
 	SynchronizeEnvironment(workspace string, environment string) error
 	DestroyEnvironment(workspace string, environment string) error
+	PauseEnvironment(workspace string, environment string, service string, stop bool) error
+	ResumeEnvironment(workspace string, environment string, service string) error
+	RestartService(workspace string, environment string, service string) error
+	RecreateService(workspace string, environment string, service string) error
+	GetEnvironmentStatus(workspace string, environment string, reconcile bool) ([]*ServiceStatus, error)
+	PlanEnvironment(workspace string, environment string) ([]string, error)
+	SetDockerEndpoint(workspace string, endpoint *model.DockerEndpoint) error
 }
 
 type LocalWorkspacesService struct {
@@ -91,6 +97,30 @@ func (wss LocalWorkspacesService) GetWorkspace(name string) (*model.Workspace, e
 	return ws, nil
 }
 
+// GetEnvironment returns the environment of the given workspace, the environment workspace field is always set
+func (wss LocalWorkspacesService) GetEnvironment(targetWorkspace string, environment string) (*model.Environment, error) {
+	_, env, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	return env, err
+}
+
+// getWorkspaceEnvironment returns the environment along with its workspace, so the workspace can be persisted after the environment changes
+func (wss LocalWorkspacesService) getWorkspaceEnvironment(targetWorkspace string, environment string) (*model.Workspace, *model.Environment, error) {
+	ws, err := wss.GetWorkspace(targetWorkspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ws == nil {
+		return nil, nil, fmt.Errorf("workspace %s not found", targetWorkspace)
+	}
+	for _, env := range ws.Environments {
+		if env.Name == environment {
+			env.Workspace = ws.Name
+			return ws, env, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("failed to find target environment %s under %s workspace", environment, targetWorkspace)
+}
+
 func (wss LocalWorkspacesService) ListWorkspaces() ([]*model.Workspace, error) {
 
 	utils.Logger.Info("Listing workspaces")
@@ -136,6 +166,17 @@ func (wss LocalWorkspacesService) DestroyWorkspace(name string) error {
 
 	}
 
+	// an empty environment name purges the volumes of every environment ever synchronized in the workspace
+	err = wss.EnvironmentService.PurgeEnvironment(&model.Environment{
+		Workspace: ws.Name,
+		Target:    model.Target{Type: "local"},
+	})
+	if err != nil {
+		utils.Logger.Error("%v", err)
+		err = fmt.Errorf("failed destroying workspace %s, failed to purge its volumes : %v", name, err)
+		return err
+	}
+
 	err = wss.PersistenceService.ClearWorkspace(ws)
 
 	if err != nil {
@@ -219,9 +260,12 @@ func getPodsForSvc(svc *corev1.Service, namespace string, k8sClient *kubernetes.
 	return pods, err
 }
 
-func (wss LocalWorkspacesService) ImportK8sEnvironment(targetWorkspace string, k8sCluster string, k8sNamespace string, k8sServer string, k8sToken string, k8sCertAuth string, excludeList []string, dbType string, dbURL string) (*model.Environment, error) {
+func (wss LocalWorkspacesService) ImportK8sEnvironment(targetWorkspace string, k8sCluster string, k8sNamespace string, k8sServer string, k8sToken string, k8sCertAuth string, excludeList []string, resourceScale float64, dbType string, dbURL string) (*model.Environment, error) {
 
 	utils.Logger.Info("Importing k8s namespace %s into workspace %s", k8sNamespace, targetWorkspace)
+	if resourceScale <= 0 {
+		return nil, fmt.Errorf("failed importing k8s namespace %s, invalid resource scale %v, expected a positive factor", k8sNamespace, resourceScale)
+	}
 	ws, err := wss.GetWorkspace(targetWorkspace)
 
 	if err != nil {
@@ -315,11 +359,14 @@ func (wss LocalWorkspacesService) ImportK8sEnvironment(targetWorkspace string, k
 		pod := pods.Items[0]
 
 		configVolumes := make(map[string]*corev1.ConfigMapVolumeSource)
+		dataVolumes := make(map[string]corev1.Volume)
 		volumes := pod.Spec.Volumes
 
 		for _, v := range volumes {
 			if v.ConfigMap != nil {
 				configVolumes[v.Name] = v.ConfigMap
+			} else if v.EmptyDir != nil || v.PersistentVolumeClaim != nil {
+				dataVolumes[v.Name] = v
 			}
 
 		}
@@ -327,11 +374,23 @@ func (wss LocalWorkspacesService) ImportK8sEnvironment(targetWorkspace string, k
 		container := pod.Spec.Containers[0]
 
 		service.Run = &model.RunConfig{
-			Cmd:    strings.Join(container.Command, " "),
-			Args:   container.Args,
-			EnVars: make([]model.EnVar, 0),
-			Ports:  []model.Port{},
-			Mounts: make(map[string]model.Mount),
+			Entrypoint: container.Command,
+			Args:       container.Args,
+			EnVars:     make([]model.EnVar, 0),
+			Ports:      []model.Port{},
+			Mounts:     make(map[string]model.Mount),
+			Resources:  getK8sResources(container.Resources, resourceScale),
+			WorkingDir: container.WorkingDir,
+			User:       getK8sUser(pod.Spec.SecurityContext, container.SecurityContext),
+			ExtraHosts: getK8sExtraHosts(pod.Spec.HostAliases),
+		}
+		service.PreRun = getK8sPreRunSteps(pod.Spec.InitContainers)
+		if pod.Spec.DNSConfig != nil {
+			service.Run.DNS = pod.Spec.DNSConfig.Nameservers
+			service.Run.DNSSearch = pod.Spec.DNSConfig.Searches
+		}
+		if pod.Spec.TerminationGracePeriodSeconds != nil {
+			service.Run.StopTimeout = fmt.Sprintf("%ds", *pod.Spec.TerminationGracePeriodSeconds)
 		}
 
 		for _, v := range container.VolumeMounts {
@@ -355,9 +414,12 @@ func (wss LocalWorkspacesService) ImportK8sEnvironment(targetWorkspace string, k
 
 				}
 				service.Run.Mounts[v.Name] = mount
+			} else if volume, ok := dataVolumes[v.Name]; ok {
+				service.Run.Mounts[v.Name] = getK8sDataMount(service.Name, volume, v)
 			}
 		}
 		service.Params["image"] = container.Image
+		service.PullPolicy = string(container.ImagePullPolicy)
 
 		for _, k8sServicePort := range k8sService.Spec.Ports {
 
@@ -534,16 +596,21 @@ func (wss LocalWorkspacesService) ImportLocalEnvironment(targetWorkspace string,
 	ApplyStatus(importedEnv, model.INACTIVE_STATUS)
 	importedEnv.Workspace = ws.Name
 	utils.Logger.Increment(10, "")
-	for _, environment := range ws.Environments {
+	var replaced *model.Environment
+	for i, environment := range ws.Environments {
 		if environment.Name == importedEnv.Name {
-			err = fmt.Errorf("failed importing environment in path %s, environment %s already exist in workspace %s", envPath, importedEnv.Name, targetWorkspace)
-
-			return nil, err
+			// re-applying an environment replaces its definition, the synchronizer reconciles the running containers
+			utils.Logger.Info("environment %s already exist in workspace %s, replacing its definition", importedEnv.Name, targetWorkspace)
+			ws.Environments[i] = importedEnv
+			replaced = environment
+			break
 		}
 
 	}
 
-	ws.Environments = append(ws.Environments, importedEnv)
+	if replaced == nil {
+		ws.Environments = append(ws.Environments, importedEnv)
+	}
 
 	if dbURL != "" && dbType != "" {
 		dbService, err := GetDBService(dbURL, dbType, "latest")
@@ -556,6 +623,10 @@ func (wss LocalWorkspacesService) ImportLocalEnvironment(targetWorkspace string,
 		importedEnv.Services["perun-db"] = dbService
 	}
 
+	if replaced != nil {
+		keepAssignedHostPorts(replaced, importedEnv)
+	}
+
 	err = wss.PersistenceService.PersistWorkspace(ws)
 	if err != nil {
 		utils.Logger.Error("%v", err)
@@ -574,7 +645,7 @@ func (wss LocalWorkspacesService) ImportLocalEnvironment(targetWorkspace string,
 
 }
 
-func (wss LocalWorkspacesService) ActivateEnvironment(targetWorkspace string, environment string) error {
+func (wss LocalWorkspacesService) ActivateEnvironment(targetWorkspace string, environment string, keepOnFailure bool) error {
 	utils.Logger.Info("Activating environment %s/%s", targetWorkspace, environment)
 	ws, err := wss.GetWorkspace(targetWorkspace)
 	if err != nil {
@@ -599,8 +670,14 @@ func (wss LocalWorkspacesService) ActivateEnvironment(targetWorkspace string, en
 	targetEnv.Workspace = ws.Name
 	utils.Logger.Increment(10, "")
 	utils.Logger.SetProgressAllocation(targetEnv.Name, 60)
-	err = wss.EnvironmentService.ActivateEnvironment(targetEnv)
+	err = wss.EnvironmentService.ActivateEnvironment(targetEnv, keepOnFailure)
 	if err != nil {
+		if isStartedStatus(targetEnv.Status) {
+			// the failed activation left containers behind, the workspace tracks them until the environment is deactivated
+			if persistErr := wss.PersistenceService.PersistWorkspace(ws); persistErr != nil {
+				utils.Logger.Error("%v", persistErr)
+			}
+		}
 		return err
 	}
 
@@ -668,9 +745,15 @@ func (wss LocalWorkspacesService) SynchronizeEnvironment(targetWorkspace string,
 		return fmt.Errorf("failed to find target environment %s under %s workspace", environment, targetWorkspace)
 	}
 
+	targetEnv.Workspace = ws.Name
 	utils.Logger.SetProgressAllocation(targetEnv.Name, 80)
 	err = wss.EnvironmentService.SyncEnvironment(targetEnv)
 
+	if err != nil {
+		return err
+	}
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
 	if err != nil {
 		return err
 	}
@@ -728,6 +811,202 @@ func (wss LocalWorkspacesService) DestroyEnvironment(targetWorkspace string, env
 	return nil
 }
 
+// PurgeEnvironmentVolumes removes the named volumes of an inactive environment, the environment may already be destroyed
+func (wss LocalWorkspacesService) PurgeEnvironmentVolumes(targetWorkspace string, environment string) error {
+	utils.Logger.Info("Purging environment %s/%s volumes", targetWorkspace, environment)
+
+	targetEnv := &model.Environment{
+		Name:      environment,
+		Workspace: targetWorkspace,
+		Target:    model.Target{Type: "local"},
+	}
+	ws, err := wss.GetWorkspace(targetWorkspace)
+	if err != nil {
+		return err
+	}
+	if ws != nil {
+		for _, env := range ws.Environments {
+			if env.Name == environment {
+				targetEnv = env
+				break
+			}
+		}
+	}
+
+	if isStartedStatus(targetEnv.Status) {
+		return fmt.Errorf("failed to purge %s/%s volumes, environment is active, deactivate it first", targetWorkspace, environment)
+	}
+
+	targetEnv.Workspace = targetWorkspace
+	err = wss.EnvironmentService.PurgeEnvironment(targetEnv)
+	if err != nil {
+		return err
+	}
+
+	utils.Logger.Info("Environment %s/%s volumes purged successfully", targetWorkspace, environment)
+	return nil
+}
+
+// PauseEnvironment pauses the environment, or a single service of it, stop releases the containers memory instead of freezing them
+func (wss LocalWorkspacesService) PauseEnvironment(targetWorkspace string, environment string, service string, stop bool) error {
+	utils.Logger.Info("Pausing environment %s/%s", targetWorkspace, environment)
+	ws, targetEnv, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return err
+	}
+
+	err = wss.EnvironmentService.PauseEnvironment(targetEnv, service, stop)
+	if err != nil {
+		return err
+	}
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info("Environment %s/%s paused successfully", targetWorkspace, environment)
+	return nil
+}
+
+func (wss LocalWorkspacesService) ResumeEnvironment(targetWorkspace string, environment string, service string) error {
+	utils.Logger.Info("Resuming environment %s/%s", targetWorkspace, environment)
+	ws, targetEnv, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return err
+	}
+
+	err = wss.EnvironmentService.ResumeEnvironment(targetEnv, service)
+	if err != nil {
+		return err
+	}
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info("Environment %s/%s resumed successfully", targetWorkspace, environment)
+	return nil
+}
+
+func (wss LocalWorkspacesService) RestartService(targetWorkspace string, environment string, service string) error {
+	utils.Logger.Info("Restarting service %s/%s/%s", targetWorkspace, environment, service)
+	ws, targetEnv, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return err
+	}
+
+	err = wss.EnvironmentService.RestartService(targetEnv, service)
+	if err != nil {
+		return err
+	}
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info("Service %s/%s/%s restarted successfully", targetWorkspace, environment, service)
+	return nil
+}
+
+// RecreateService replaces the service container with a fresh one built from the current environment spec
+func (wss LocalWorkspacesService) RecreateService(targetWorkspace string, environment string, service string) error {
+	utils.Logger.Info("Recreating service %s/%s/%s", targetWorkspace, environment, service)
+	ws, targetEnv, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return err
+	}
+
+	err = wss.EnvironmentService.RecreateService(targetEnv, service)
+	if err != nil {
+		return err
+	}
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info("Service %s/%s/%s recreated successfully", targetWorkspace, environment, service)
+	return nil
+}
+
+// PlanEnvironment returns the ordered steps the environment activation would take, the workspace is left untouched
+func (wss LocalWorkspacesService) PlanEnvironment(targetWorkspace string, environment string) ([]string, error) {
+	utils.Logger.Info("Planning environment %s/%s activation", targetWorkspace, environment)
+	targetEnv, err := wss.GetEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return wss.EnvironmentService.PlanEnvironment(targetEnv)
+}
+
+// GetEnvironmentStatus returns the environment services state as observed in docker, reconcile saves the observed statuses in the workspace
+func (wss LocalWorkspacesService) GetEnvironmentStatus(targetWorkspace string, environment string, reconcile bool) ([]*ServiceStatus, error) {
+	ws, targetEnv, err := wss.getWorkspaceEnvironment(targetWorkspace, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := wss.EnvironmentService.GetEnvironmentStatus(targetEnv)
+	if err != nil {
+		return nil, err
+	}
+	if !reconcile {
+		return statuses, nil
+	}
+
+	for _, status := range statuses {
+		if service := targetEnv.Services[status.Service]; service != nil && service.Status != status.Status {
+			utils.Logger.Info("service %s/%s/%s status drifted from %s to %s", targetWorkspace, environment, status.Service, service.Status, status.Status)
+			service.Status = status.Status
+		}
+	}
+	targetEnv.Status = getObservedEnvironmentStatus(statuses)
+
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// SetDockerEndpoint points the workspace at another docker daemon, a nil endpoint goes back to the DOCKER_* environment variables,
+// the running environments would be left behind on the previous daemon so they must be deactivated first
+func (wss LocalWorkspacesService) SetDockerEndpoint(targetWorkspace string, endpoint *model.DockerEndpoint) error {
+	ws, err := wss.GetWorkspace(targetWorkspace)
+	if err != nil {
+		return err
+	}
+	if ws == nil {
+		return fmt.Errorf("workspace %s not found", targetWorkspace)
+	}
+
+	for _, env := range ws.Environments {
+		if isStartedStatus(env.Status) {
+			return fmt.Errorf("failed to change workspace %s docker endpoint, environment %s is %s, deactivate it first", targetWorkspace, env.Name, env.Status)
+		}
+	}
+
+	if endpoint != nil {
+		cli, err := dockerClients.ForEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+		if _, err := cli.Ping(context.Background()); err != nil {
+			return fmt.Errorf("failed to reach docker host %s : %v", cli.DaemonHost(), err)
+		}
+	}
+
+	ws.Docker = endpoint
+	err = wss.PersistenceService.PersistWorkspace(ws)
+	if err != nil {
+		return err
+	}
+	utils.Logger.Info("Workspace %s docker endpoint updated successfully", targetWorkspace)
+	return nil
+}
+
 func ApplyStatus(env *model.Environment, status string) error {
 
 	env.Status = status
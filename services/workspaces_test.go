@@ -54,8 +54,8 @@ func (m *DummyEnvironmentService) CreateEnvironment(name string) (*model.Environ
 	return args.Get(0).(*model.Environment), args.Error(1)
 }
 
-func (m *DummyEnvironmentService) ActivateEnvironment(env *model.Environment) error {
-	args := m.Called(env)
+func (m *DummyEnvironmentService) ActivateEnvironment(env *model.Environment, keepOnFailure bool) error {
+	args := m.Called(env, keepOnFailure)
 	return args.Error(0)
 }
 
@@ -74,6 +74,41 @@ func (m *DummyEnvironmentService) SyncEnvironment(env *model.Environment) error
 	return args.Error(0)
 }
 
+func (m *DummyEnvironmentService) PurgeEnvironment(env *model.Environment) error {
+	args := m.Called(env)
+	return args.Error(0)
+}
+
+func (m *DummyEnvironmentService) PauseEnvironment(env *model.Environment, service string, stop bool) error {
+	args := m.Called(env, service, stop)
+	return args.Error(0)
+}
+
+func (m *DummyEnvironmentService) ResumeEnvironment(env *model.Environment, service string) error {
+	args := m.Called(env, service)
+	return args.Error(0)
+}
+
+func (m *DummyEnvironmentService) RestartService(env *model.Environment, service string) error {
+	args := m.Called(env, service)
+	return args.Error(0)
+}
+
+func (m *DummyEnvironmentService) RecreateService(env *model.Environment, service string) error {
+	args := m.Called(env, service)
+	return args.Error(0)
+}
+
+func (m *DummyEnvironmentService) GetEnvironmentStatus(env *model.Environment) ([]*ServiceStatus, error) {
+	args := m.Called(env)
+	return args.Get(0).([]*ServiceStatus), args.Error(1)
+}
+
+func (m *DummyEnvironmentService) PlanEnvironment(env *model.Environment) ([]string, error) {
+	args := m.Called(env)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 type DummyAnalyzerService struct {
 	mock.Mock
 }
@@ -177,6 +212,7 @@ func TestDestroyWorkspace(t *testing.T) {
 	wss.EnvironmentService = es
 
 	es.On("DestroyEnvironment", testEnv).Return(nil)
+	es.On("PurgeEnvironment", &model.Environment{Workspace: "test", Target: model.Target{Type: "local"}}).Return(nil)
 	err := wss.DestroyWorkspace("test")
 
 	assert.Nil(t, err)
@@ -209,9 +245,9 @@ func TestActivateEnvironment(t *testing.T) {
 	es := new(DummyEnvironmentService)
 	wss.EnvironmentService = es
 
-	es.On("ActivateEnvironment", testEnv).Return(nil)
+	es.On("ActivateEnvironment", testEnv, false).Return(nil)
 
-	err := wss.ActivateEnvironment("test", "testEnv")
+	err := wss.ActivateEnvironment("test", "testEnv", false)
 	assert.Nil(t, err)
 
 	ps.AssertExpectations(t)
@@ -268,6 +304,7 @@ func TestSynchronizeEnvironment(t *testing.T) {
 	}
 
 	ps.On("GetWorkspace", "test").Return(expectedWS, nil)
+	ps.On("PersistWorkspace", expectedWS).Return(nil)
 
 	es := new(DummyEnvironmentService)
 	wss.EnvironmentService = es
@@ -288,3 +325,121 @@ func TestDestroyEnvironment(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestPauseEnvironment(t *testing.T) {
+	wss := GetWorkspaceService()
+
+	ps := new(DummyPersistenceService)
+
+	wss.PersistenceService = ps
+	testEnv := &model.Environment{
+		Name: "testEnv",
+	}
+	expectedWS := &model.Workspace{
+		Name: "test",
+		Environments: []*model.Environment{
+			testEnv,
+		},
+	}
+
+	ps.On("GetWorkspace", "test").Return(expectedWS, nil)
+	ps.On("PersistWorkspace", expectedWS).Return(nil)
+
+	es := new(DummyEnvironmentService)
+	wss.EnvironmentService = es
+
+	es.On("PauseEnvironment", testEnv, "api", true).Return(nil)
+
+	err := wss.PauseEnvironment("test", "testEnv", "api", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", testEnv.Workspace)
+
+	ps.AssertExpectations(t)
+	es.AssertExpectations(t)
+}
+
+func TestPauseEnvironmentNotActive(t *testing.T) {
+	es := LocalEnvironmentService{}
+
+	err := es.PauseEnvironment(&model.Environment{Name: "testEnv", Status: model.INACTIVE_STATUS}, "", false)
+	assert.NotNil(t, err)
+
+	err = es.ResumeEnvironment(&model.Environment{Name: "testEnv", Status: model.INACTIVE_STATUS}, "")
+	assert.NotNil(t, err)
+}
+
+func TestGetEnvironmentStatusReconcile(t *testing.T) {
+	wss := GetWorkspaceService()
+
+	ps := new(DummyPersistenceService)
+
+	wss.PersistenceService = ps
+	testEnv := &model.Environment{
+		Name:   "testEnv",
+		Status: model.ACTIVE_STATUS,
+		Services: map[string]*model.Service{
+			"api": {Name: "api", Status: model.ACTIVE_STATUS},
+			"db":  {Name: "db", Status: model.ACTIVE_STATUS},
+		},
+	}
+	expectedWS := &model.Workspace{
+		Name: "test",
+		Environments: []*model.Environment{
+			testEnv,
+		},
+	}
+
+	ps.On("GetWorkspace", "test").Return(expectedWS, nil)
+	ps.On("PersistWorkspace", expectedWS).Return(nil)
+
+	es := new(DummyEnvironmentService)
+	wss.EnvironmentService = es
+
+	es.On("GetEnvironmentStatus", testEnv).Return([]*ServiceStatus{
+		{Service: "api", State: "exited", Status: model.STOPPED_STATUS},
+		{Service: "db", State: MISSING_STATE, Status: model.INACTIVE_STATUS},
+	}, nil)
+
+	statuses, err := wss.GetEnvironmentStatus("test", "testEnv", true)
+	assert.Nil(t, err)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, model.STOPPED_STATUS, testEnv.Services["api"].Status)
+	assert.Equal(t, model.INACTIVE_STATUS, testEnv.Services["db"].Status)
+	assert.Equal(t, model.STOPPED_STATUS, testEnv.Status)
+
+	ps.AssertExpectations(t)
+	es.AssertExpectations(t)
+}
+
+func TestPlanEnvironment(t *testing.T) {
+	setTestLogger(t)
+	wss := GetWorkspaceService()
+
+	ps := new(DummyPersistenceService)
+
+	wss.PersistenceService = ps
+	testEnv := &model.Environment{
+		Name:   "testEnv",
+		Status: model.INACTIVE_STATUS,
+	}
+	expectedWS := &model.Workspace{
+		Name: "test",
+		Environments: []*model.Environment{
+			testEnv,
+		},
+	}
+
+	ps.On("GetWorkspace", "test").Return(expectedWS, nil)
+
+	es := new(DummyEnvironmentService)
+	wss.EnvironmentService = es
+
+	es.On("PlanEnvironment", testEnv).Return([]string{"create network test-testEnv"}, nil)
+
+	plan, err := wss.PlanEnvironment("test", "testEnv")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"create network test-testEnv"}, plan)
+
+	ps.AssertNotCalled(t, "PersistWorkspace", expectedWS)
+	es.AssertExpectations(t)
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+
+	args, err := SplitCommand(`java -jar "/opt/my app/app.jar" --name='perun dev' a\ b`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"java", "-jar", "/opt/my app/app.jar", "--name=perun dev", "a b"}, args)
+
+	_, err = SplitCommand(`echo "unterminated`)
+	assert.EqualError(t, err, `unterminated " quote in echo "unterminated`)
+}
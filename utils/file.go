@@ -1,9 +1,15 @@
 package utils
 
 import (
+	"archive/tar"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/pkg/fileutils"
 )
 
 // DownloadFile will download a url to a local file. It's efficient because it will
@@ -33,3 +39,132 @@ func FileExists(filename string) bool {
 	}
 	return !info.IsDir()
 }
+
+// TarDirectory streams the content of the given directory as a tar archive, the exclude patterns follow the .dockerignore
+// rules of docker build, ** wildcards included, and a path matching an exception pattern (!pattern) is always kept
+func TarDirectory(dir string, excludes []string) (io.ReadCloser, error) {
+
+	matcher, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude patterns : %v", err)
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			if relPath == "." {
+				return nil
+			}
+
+			excluded, err := matcher.Matches(relPath)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				if !info.IsDir() {
+					return nil
+				}
+				// an excluded directory is still walked when an exception pattern may match a path under it
+				if matcher.Exclusions() {
+					dirPrefix := relPath + string(filepath.Separator)
+					for _, pattern := range matcher.Patterns() {
+						if pattern.Exclusion() && strings.HasPrefix(pattern.String()+string(filepath.Separator), dirPrefix) {
+							return nil
+						}
+					}
+				}
+				return filepath.SkipDir
+			}
+
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				link, err = os.Readlink(path)
+				if err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relPath)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			// like docker build, the context files are owned by root in the image
+			header.Uid, header.Gid = 0, 0
+			header.Uname, header.Gname = "", ""
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(tw, file)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader, nil
+}
+
+// ReadIgnoreFile reads a .dockerignore file into exclude patterns the way docker build does, a missing file yields no patterns
+func ReadIgnoreFile(path string) ([]string, error) {
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0)
+	for i, line := range strings.Split(string(data), "\n") {
+		if i == 0 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		exception := strings.HasPrefix(line, "!")
+		if exception {
+			line = strings.TrimSpace(line[1:])
+		}
+		if line != "" {
+			line = filepath.ToSlash(filepath.Clean(line))
+			if len(line) > 1 && line[0] == '/' {
+				line = line[1:]
+			}
+		}
+		if exception {
+			line = "!" + line
+		}
+		patterns = append(patterns, line)
+	}
+
+	return patterns, nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIgnoreFile(t *testing.T) {
+
+	dir := t.TempDir()
+	patterns, err := ReadIgnoreFile(filepath.Join(dir, ".dockerignore"))
+	assert.Nil(t, err)
+	assert.Len(t, patterns, 0)
+
+	content := "\ufeff# comment\nnode_modules/\n\n  /build  \n**/*.log\n! build/keep.txt\n./docs/../tmp\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(content), 0644))
+
+	patterns, err = ReadIgnoreFile(filepath.Join(dir, ".dockerignore"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node_modules", "build", "**/*.log", "!build/keep.txt", "tmp"}, patterns)
+}
+
+func TestTarDirectory(t *testing.T) {
+
+	dir := t.TempDir()
+	for _, file := range []string{"Dockerfile", "main.go", "app.log", "src/debug.log", "src/lib.go", "build/out.bin", "build/keep.txt", "node_modules/pkg/index.js"} {
+		assert.Nil(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), os.ModePerm))
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, file), []byte(file), 0644))
+	}
+
+	reader, err := TarDirectory(dir, []string{"node_modules", "build", "**/*.log", "!build/keep.txt"})
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	names := make([]string, 0)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"Dockerfile", "build/keep.txt", "main.go", "src/", "src/lib.go"}, names)
+
+	_, err = TarDirectory(dir, []string{"!"})
+	assert.NotNil(t, err)
+}
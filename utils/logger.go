@@ -3,6 +3,7 @@ package utils
 import (
 	"io"
 	"os"
+	"sync"
 
 	"github.com/schollz/progressbar/v3"
 	log "github.com/sirupsen/logrus"
@@ -15,6 +16,7 @@ type ILogger struct {
 	ignoreIncrements bool
 	Verbose          bool
 	allocationMap    map[string]int
+	lock             sync.Mutex
 }
 
 var Logger *ILogger
@@ -97,6 +99,8 @@ func (l *ILogger) Increment(increment int, description string) {
 	if l.ignoreIncrements || l.Verbose {
 		return
 	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
 	if description != "" {
 		l.bar.Describe(description)
 	}